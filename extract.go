@@ -18,6 +18,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"os"
+	"strings"
 )
 
 // extracts parses the given structure.xml file into corresponding
@@ -58,8 +59,8 @@ type file struct {
 	Class            *class           `xml:"class,omitempty"`
 	Interface        *iface           `xml:"interface,omitempty"`
 	Trait            *trait           `xml:"trait,omitempty"`
-	Constants        []constant       `xml:"constant,omitempty"` // TODO
-	Functions        []fn             `xml:"function,omitempty"` // TODO
+	Constants        []constant       `xml:"constant,omitempty"`
+	Functions        []fn             `xml:"function,omitempty"`
 
 	// TODO: includes, parse_markers
 }
@@ -72,20 +73,39 @@ type docblock struct {
 	Tags            []tag  `xml:"tag,omitempty"`
 }
 
+// summary returns the description of d, without its code examples.
 func (d *docblock) summary() string {
 	if d == nil {
 		return ""
 	}
 	s := d.Description
-	if d.LongDescription != "" {
+	long, _ := splitExamples(d.LongDescription)
+	if long != "" {
 		if d.Description != "" {
 			s += "\n\n"
 		}
-		s += d.LongDescription
+		s += long
 	}
 	return s
 }
 
+// examples returns the code examples in the description of d.
+func (d *docblock) examples() []example {
+	if d == nil {
+		return nil
+	}
+	_, examples := splitExamples(d.LongDescription)
+	return examples
+}
+
+// shortSummary returns the description of d on a single line.
+func (d *docblock) shortSummary() string {
+	if d == nil {
+		return ""
+	}
+	return collapseSpace(d.Description)
+}
+
 type tag struct {
 	Name        string `xml:"name,attr,omitempty"`
 	Description string `xml:"description,attr,omitempty"`
@@ -93,7 +113,7 @@ type tag struct {
 	Type        string `xml:"type,attr,omitempty"`
 	LinkOrRef   string `xml:"link,attr,omitempty"`
 	Version     string `xml:"version,omitempty"`
-	MethodName  string `xml:"method_name,omitempty"`
+	MethodName  string `xml:"method_name,attr,omitempty"`
 }
 
 type namespaceAlias struct {
@@ -124,7 +144,7 @@ type property struct {
 	Name          string    `xml:"name,omitempty"`
 	FullName      string    `xml:"full_name,omitempty"`
 	Docblock      *docblock `xml:"docblock,omitempty"`
-	Default       string    `xml:"property,omitempty"`
+	Default       string    `xml:"default,omitempty"`
 	InheritedFrom string    `xml:"inherited_from,omitempty"`
 }
 
@@ -159,10 +179,32 @@ type fn struct {
 type argument struct {
 	Line        string `xml:"line,attr,omitempty"`
 	ByReference bool   `xml:"by_reference,attr"`
+	Variadic    bool   `xml:"variadic,attr,omitempty"`
 
 	Name    string `xml:"name,omitempty"`
 	Type    string `xml:"type,omitempty"`
-	Default string `xml:"property,omitempty"`
+	Default string `xml:"default,omitempty"`
+}
+
+// defaultValue returns the default value of a as it would be written in code,
+// or "" if a has no default.
+func (a argument) defaultValue() string {
+	return normalizeDefault(a.Default)
+}
+
+// defaultValue returns the default value of p as it would be written in code,
+// or "" if p has no default.
+func (p property) defaultValue() string {
+	return normalizeDefault(p.Default)
+}
+
+// normalizeDefault spells the default value v the way PHP code usually does.
+// phpDocumentor writes null as NULL.
+func normalizeDefault(v string) string {
+	if strings.EqualFold(v, "null") {
+		return "null"
+	}
+	return v
 }
 
 type iface struct {
@@ -175,7 +217,7 @@ type iface struct {
 	Docblock  *docblock  `xml:"docblock,omitempty"`
 	Methods   []method   `xml:"method,omitempty"`
 	Constants []constant `xml:"constant,omitempty"`
-	Extends   string     `xml:"extends,omitempty"` // TODO
+	Extends   []string   `xml:"extends,omitempty"`
 }
 
 type constant struct {
@@ -224,3 +266,28 @@ func (d *docblock) param(name string) string {
 	}
 	return ""
 }
+
+func (d *docblock) tag(name string) *tag {
+	if d == nil {
+		return nil
+	}
+	for i, t := range d.Tags {
+		if t.Name == name {
+			return &d.Tags[i]
+		}
+	}
+	return nil
+}
+
+func (d *docblock) tags(name string) []tag {
+	if d == nil {
+		return nil
+	}
+	var tags []tag
+	for _, t := range d.Tags {
+		if t.Name == name {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
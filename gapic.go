@@ -0,0 +1,190 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+)
+
+// service documents the API service a generated GAPIC client calls, with
+// the methods of the client grouped by purpose.
+type service struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address,omitempty"`
+	Port    string `yaml:"port,omitempty"`
+	RPCs    []rpc  `yaml:"rpcs,omitempty"`
+	// PathTemplates are the UIDs of the methods formatting and parsing
+	// resource names, like productName() and parseName().
+	PathTemplates []string `yaml:"pathTemplates,omitempty"`
+	// Operations are the UIDs of the methods managing long running
+	// operations.
+	Operations []string `yaml:"operations,omitempty"`
+}
+
+// rpc represents a method of a GAPIC client calling an RPC of its service.
+type rpc struct {
+	// Name is the name of the RPC in the .proto file, like GetProduct.
+	Name string `yaml:"name"`
+	UID  string `yaml:"uid"`
+	// Request and Response are the UIDs of the messages of the RPC. The
+	// response is omitted if it is not part of the project.
+	Request  string `yaml:"request"`
+	Response string `yaml:"response,omitempty"`
+	// LongRunning reports whether the method returns an operation, which
+	// results in the response once done, rather than the response itself.
+	LongRunning bool `yaml:"longRunning,omitempty"`
+}
+
+// operationResponse is the type GAPIC clients return for long running
+// operations.
+const operationResponse = `\Google\ApiCore\OperationResponse`
+
+// operationsHelpers are the methods GAPIC clients of services with long
+// running operations declare.
+var operationsHelpers = map[string]bool{
+	"getOperationsClient": true,
+	"resumeOperation":     true,
+}
+
+// gapicService returns the service called by c, if c is a GAPIC client: a
+// class named like FooGapicClient declaring a SERVICE_NAME constant.
+//
+// The RPC of a method is named after the method, and its messages are found
+// by the names protoc gives them in the namespace of the client, or the
+// parent namespace for clients in a Gapic namespace. Only the methods with a
+// request message are RPCs, which leaves out the constructor and any other
+// helpers. RPCs returning a message, rather than an operation or a page of
+// results, are documented by the returned message.
+func gapicService(c *class, ix *index) *service {
+	if !strings.HasSuffix(c.Name, "GapicClient") {
+		return nil
+	}
+	consts := map[string]string{}
+	for _, k := range c.Constants {
+		if k.InheritedFrom == "" {
+			consts[k.Name] = strings.Trim(k.Value, `'"`)
+		}
+	}
+	name, ok := consts["SERVICE_NAME"]
+	if !ok {
+		return nil
+	}
+	s := &service{
+		Name:    name,
+		Address: consts["SERVICE_ADDRESS"],
+		Port:    consts["DEFAULT_SERVICE_PORT"],
+	}
+
+	ns := namespaceOf(c.FullName)
+	if strings.HasSuffix(ns, "\\Gapic") {
+		ns = namespaceOf(ns)
+	}
+	message := func(uid string) string {
+		if ix.isMessage(uid) {
+			return uid
+		}
+		return ""
+	}
+	for _, m := range c.Methods {
+		if m.InheritedFrom != "" || m.Visibility != "public" || strings.HasPrefix(m.Name, "__") {
+			continue
+		}
+		switch {
+		case operationsHelpers[m.Name]:
+			s.Operations = append(s.Operations, m.FullName)
+		case m.Static && (m.Name == "parseName" || strings.HasSuffix(m.Name, "Name")):
+			s.PathTemplates = append(s.PathTemplates, m.FullName)
+		case !m.Static:
+			r := rpc{
+				Name: strings.ToUpper(m.Name[:1]) + m.Name[1:],
+				UID:  m.FullName,
+			}
+			r.Request = message(ns + "\\" + r.Name + "Request")
+			if r.Request == "" {
+				continue
+			}
+			r.Response = message(ns + "\\" + r.Name + "Response")
+			if ret := m.Docblock.tag("return"); ret != nil {
+				r.LongRunning = ret.Type == operationResponse
+				if r.Response == "" {
+					r.Response = message(ret.Type)
+				}
+			}
+			s.RPCs = append(s.RPCs, r)
+		}
+	}
+	return s
+}
+
+// gapicBase returns the UID of the GAPIC client c extends, like the
+// ImageAnnotatorGapicClient extended by ImageAnnotatorClient, or "" if c
+// does not extend one.
+func gapicBase(c *class, ix *index) string {
+	if base, ok := ix.classes[c.Extends]; ok && gapicService(base, ix) != nil {
+		return c.Extends
+	}
+	return ""
+}
+
+// flattenedBases returns the UIDs of the classes whose members are
+// documented on the page of c when GAPIC clients are flattened: the GAPIC
+// client c extends and the ancestors of the GAPIC client that are part of
+// the project, nearest first.
+func flattenedBases(c *class, ix *index) []string {
+	gapic := gapicBase(c, ix)
+	if gapic == "" {
+		return nil
+	}
+	bases := []string{gapic}
+	ancestors := ix.inheritance(gapic)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if _, ok := ix.classes[ancestors[i]]; ok {
+			bases = append(bases, ancestors[i])
+		}
+	}
+	return bases
+}
+
+// clientScoped returns the UID of the member uid of a GAPIC client when it is
+// documented as a member of client, the class extending the GAPIC client.
+// uid is returned as is if it is not the UID of a member.
+func clientScoped(uid, client string) string {
+	i := strings.Index(uid, "::")
+	if i < 0 {
+		return uid
+	}
+	return client + uid[i:]
+}
+
+// scoped returns a copy of s documenting client, which extends the GAPIC
+// client s was found in.
+func (s *service) scoped(client string) *service {
+	scoped := *s
+	scoped.RPCs = nil
+	for _, r := range s.RPCs {
+		r.UID = clientScoped(r.UID, client)
+		scoped.RPCs = append(scoped.RPCs, r)
+	}
+	scopeAll := func(uids []string) []string {
+		var out []string
+		for _, uid := range uids {
+			out = append(out, clientScoped(uid, client))
+		}
+		return out
+	}
+	scoped.PathTemplates = scopeAll(s.PathTemplates)
+	scoped.Operations = scopeAll(s.Operations)
+	return &scoped
+}
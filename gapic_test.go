@@ -0,0 +1,121 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGapicService(t *testing.T) {
+	message := func(uid string) file {
+		return file{Class: &class{FullName: uid, Extends: protobufMessage}}
+	}
+	client := &class{
+		Name:     "ProductSearchGapicClient",
+		FullName: `\Foo\V1\Gapic\ProductSearchGapicClient`,
+		Constants: []constant{
+			{Name: "SERVICE_NAME", Value: "'foo.v1.ProductSearch'"},
+			{Name: "SERVICE_ADDRESS", Value: "'foo.googleapis.com'"},
+			{Name: "DEFAULT_SERVICE_PORT", Value: "443"},
+		},
+		Methods: []method{
+			{Name: "productName", FullName: `\Foo\V1\Gapic\ProductSearchGapicClient::productName()`, Static: true, Visibility: "public"},
+			{Name: "parseName", FullName: `\Foo\V1\Gapic\ProductSearchGapicClient::parseName()`, Static: true, Visibility: "public"},
+			{Name: "getClientDefaults", FullName: `\Foo\V1\Gapic\ProductSearchGapicClient::getClientDefaults()`, Static: true, Visibility: "private"},
+			{Name: "getOperationsClient", FullName: `\Foo\V1\Gapic\ProductSearchGapicClient::getOperationsClient()`, Visibility: "public"},
+			{Name: "__construct", FullName: `\Foo\V1\Gapic\ProductSearchGapicClient::__construct()`, Visibility: "public"},
+			{
+				Name:       "getProduct",
+				FullName:   `\Foo\V1\Gapic\ProductSearchGapicClient::getProduct()`,
+				Visibility: "public",
+				Docblock:   &docblock{Tags: []tag{{Name: "return", Type: `\Foo\V1\Product`}}},
+			},
+			{Name: "listProducts", FullName: `\Foo\V1\Gapic\ProductSearchGapicClient::listProducts()`, Visibility: "public"},
+			{
+				Name:       "purgeProducts",
+				FullName:   `\Foo\V1\Gapic\ProductSearchGapicClient::purgeProducts()`,
+				Visibility: "public",
+				Docblock:   &docblock{Tags: []tag{{Name: "return", Type: operationResponse}}},
+			},
+			{Name: "withRetries", FullName: `\Foo\V1\Gapic\ProductSearchGapicClient::withRetries()`, Visibility: "public"},
+			{Name: "close", FullName: `\Foo\V1\Gapic\ProductSearchGapicClient::close()`, Visibility: "public", InheritedFrom: `\Foo\GapicClientTrait`},
+		},
+	}
+	ix := newIndex(&project{Files: []file{
+		{Class: client},
+		message(`\Foo\V1\Product`),
+		message(`\Foo\V1\GetProductRequest`),
+		message(`\Foo\V1\ListProductsRequest`),
+		message(`\Foo\V1\ListProductsResponse`),
+		message(`\Foo\V1\PurgeProductsRequest`),
+		message(`\Foo\V1\PurgeProductsResponse`),
+	}})
+
+	got := gapicService(client, ix)
+	want := &service{
+		Name:    "foo.v1.ProductSearch",
+		Address: "foo.googleapis.com",
+		Port:    "443",
+		RPCs: []rpc{
+			{
+				Name:     "GetProduct",
+				UID:      `\Foo\V1\Gapic\ProductSearchGapicClient::getProduct()`,
+				Request:  `\Foo\V1\GetProductRequest`,
+				Response: `\Foo\V1\Product`,
+			},
+			{
+				Name:     "ListProducts",
+				UID:      `\Foo\V1\Gapic\ProductSearchGapicClient::listProducts()`,
+				Request:  `\Foo\V1\ListProductsRequest`,
+				Response: `\Foo\V1\ListProductsResponse`,
+			},
+			{
+				Name:        "PurgeProducts",
+				UID:         `\Foo\V1\Gapic\ProductSearchGapicClient::purgeProducts()`,
+				Request:     `\Foo\V1\PurgeProductsRequest`,
+				Response:    `\Foo\V1\PurgeProductsResponse`,
+				LongRunning: true,
+			},
+		},
+		PathTemplates: []string{
+			`\Foo\V1\Gapic\ProductSearchGapicClient::productName()`,
+			`\Foo\V1\Gapic\ProductSearchGapicClient::parseName()`,
+		},
+		Operations: []string{`\Foo\V1\Gapic\ProductSearchGapicClient::getOperationsClient()`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("gapicService got %+v, want %+v", got, want)
+	}
+
+	if s := gapicService(&class{Name: "ProductSearchClient", Constants: client.Constants}, ix); s != nil {
+		t.Errorf("gapicService got %+v for a class not named like a GAPIC client, want nil", s)
+	}
+}
+
+func TestClientScoped(t *testing.T) {
+	client := `\Foo\V1\FooClient`
+	for _, test := range []struct {
+		uid, want string
+	}{
+		{`\Foo\V1\Gapic\FooGapicClient::getBar()`, `\Foo\V1\FooClient::getBar()`},
+		{`\Foo\V1\Gapic\FooGapicClient::SERVICE_NAME`, `\Foo\V1\FooClient::SERVICE_NAME`},
+		{`\Foo\V1\Gapic\FooGapicClient`, `\Foo\V1\Gapic\FooGapicClient`},
+	} {
+		if got := clientScoped(test.uid, client); got != test.want {
+			t.Errorf("clientScoped(%q) got %q, want %q", test.uid, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// index holds the types of a project by UID, to resolve the relationships
+// between them.
+type index struct {
+	classes    map[string]*class
+	interfaces map[string]*iface
+	traits     map[string]*trait
+	// derived holds the UIDs of the classes directly extending each class.
+	derived map[string][]string
+	// members holds references to the members declared by each type, to
+	// resolve the members other types inherit.
+	members map[string]*reference
+}
+
+// newIndex indexes the types of p, skipping tests.
+func newIndex(p *project) *index {
+	ix := &index{
+		classes:    map[string]*class{},
+		interfaces: map[string]*iface{},
+		traits:     map[string]*trait{},
+		derived:    map[string][]string{},
+		members:    map[string]*reference{},
+	}
+	for _, f := range p.Files {
+		if isTest(f) {
+			continue
+		}
+		if c := f.Class; c != nil {
+			ix.classes[c.FullName] = c
+			if c.Extends != "" {
+				ix.derived[c.Extends] = append(ix.derived[c.Extends], c.FullName)
+			}
+			ix.addMembers(c.Properties, c.Methods, c.Constants)
+		}
+		if t := f.Trait; t != nil {
+			ix.traits[t.FullName] = t
+			ix.addMembers(t.Properties, t.Methods, nil)
+		}
+		if i := f.Interface; i != nil {
+			ix.interfaces[i.FullName] = i
+			ix.addMembers(nil, i.Methods, i.Constants)
+		}
+	}
+	for _, d := range ix.derived {
+		sort.Strings(d)
+	}
+	return ix
+}
+
+// addMembers indexes the given members, skipping the inherited ones.
+func (ix *index) addMembers(props []property, methods []method, consts []constant) {
+	for _, p := range props {
+		if p.InheritedFrom == "" {
+			ix.members[p.FullName] = propertyRef(p)
+		}
+	}
+	for _, m := range methods {
+		if m.InheritedFrom == "" {
+			ix.members[m.FullName] = methodRef(m)
+		}
+	}
+	for _, c := range consts {
+		if c.InheritedFrom == "" {
+			ix.members[c.FullName] = constantRef(c)
+		}
+	}
+}
+
+// inheritedRef returns a reference to the member uid, inherited from the
+// type from. The member is resolved against the type declaring it, and
+// fallback is used if that type is not part of the project.
+func (ix *index) inheritedRef(uid, from string, fallback *reference) *reference {
+	r := fallback
+	if declared, ok := ix.members[uid]; ok {
+		r = declared
+	}
+	inherited := *r
+	inherited.Parent = from
+	return &inherited
+}
+
+// isTest reports whether f is a test file, which is never documented.
+func isTest(f file) bool {
+	return strings.HasPrefix(f.Path, "tests")
+}
+
+// inheritance returns the UIDs of the ancestors of the class uid, starting
+// with the most distant one. The chain ends with the first ancestor that is
+// not part of the project, like \Google\Protobuf\Internal\Message.
+func (ix *index) inheritance(uid string) []string {
+	var chain []string
+	seen := map[string]bool{uid: true}
+	for c := ix.classes[uid]; c != nil && c.Extends != "" && !seen[c.Extends]; c = ix.classes[c.Extends] {
+		seen[c.Extends] = true
+		chain = append([]string{c.Extends}, chain...)
+	}
+	return chain
+}
+
+// implements returns the UIDs of every interface the class uid implements,
+// including the interfaces implemented by its ancestors and the interfaces
+// those interfaces extend.
+func (ix *index) implements(uid string) []string {
+	var uids []string
+	seen := map[string]bool{}
+	var add func(ifaces []string)
+	add = func(ifaces []string) {
+		for _, i := range ifaces {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			uids = append(uids, i)
+			if parent, ok := ix.interfaces[i]; ok {
+				add(parent.Extends)
+			}
+		}
+	}
+	visited := map[string]bool{}
+	for c := ix.classes[uid]; c != nil && !visited[c.FullName]; c = ix.classes[c.Extends] {
+		visited[c.FullName] = true
+		add(c.Implements)
+	}
+	return uids
+}
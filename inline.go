@@ -0,0 +1,378 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// inlineTag matches the inline tags {@see target text} and
+// {@link target text}. The target and text are optional.
+var inlineTag = regexp.MustCompile(`\{@(see|link)(?:\s+([^\s}]+))?(?:\s+([^}]*?))?\s*\}`)
+
+// rewriteInlineTags rewrites the inline tags in the docblocks of f into
+// DocFX cross references and Markdown links. src is the PHP source of f, if
+// available, to recover the targets phpDocumentor dropped, as in
+// "created by {@see}". f should be a copy made by copyDocblocks, since its
+// docblocks are rewritten in place.
+func rewriteInlineTags(f *file, src string) {
+	scope := nameScope{namespace: fileNamespace(f)}
+	var lines []string
+	if src != "" {
+		scope = fileScope(src)
+		lines = strings.Split(src, "\n")
+	}
+	for _, d := range f.docblocks() {
+		var targets []inlineTarget
+		if lines != nil && strings.Contains(d.text(), "{@") {
+			targets = sourceTargets(lines, d.Line)
+		}
+		scope.class = d.class
+		rewriteDocblock(d.docblock, scope, targets)
+	}
+}
+
+// rewriteDocblock rewrites the inline tags of d. targets are the inline tags
+// of d, in order, as written in the source. They are only used if the source
+// and d have the same number of inline tags.
+func rewriteDocblock(d *docblock, scope nameScope, targets []inlineTarget) {
+	if len(inlineTag.FindAllString(d.text(), -1)) != len(targets) {
+		targets = nil
+	}
+	n := 0
+	rewrite := func(s string) string {
+		var b strings.Builder
+		last := 0
+		for _, m := range inlineTag.FindAllStringSubmatchIndex(s, -1) {
+			target, text := submatch(s, m, 2), submatch(s, m, 3)
+			if targets != nil {
+				target = targets[n].target
+				if text == "" {
+					text = targets[n].text
+				}
+			}
+			n++
+			link := inlineLink(target, text, scope)
+			start, end := m[0], m[1]
+			if link == "" {
+				// Drop a space next to a dropped tag too.
+				if start > last && s[start-1] == ' ' {
+					start--
+				} else if end < len(s) && s[end] == ' ' {
+					end++
+				}
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(link)
+			last = end
+		}
+		b.WriteString(s[last:])
+		return b.String()
+	}
+	d.Description = rewrite(d.Description)
+	d.LongDescription = rewrite(d.LongDescription)
+	for i := range d.Tags {
+		d.Tags[i].Description = rewrite(d.Tags[i].Description)
+	}
+}
+
+// submatch returns the submatch i of the match m of a regexp in s, or "" if
+// the submatch did not participate in the match.
+func submatch(s string, m []int, i int) string {
+	if m[2*i] < 0 {
+		return ""
+	}
+	return s[m[2*i]:m[2*i+1]]
+}
+
+// inlineLink returns the link for an inline tag with the given target and
+// text. URLs become Markdown links and structural elements become <xref>
+// tags. If there is no target, like in the "{@see}" phpDocumentor leaves
+// when it cannot parse the target, the text is returned on its own.
+func inlineLink(target, text string, scope nameScope) string {
+	switch {
+	case target == "":
+		return text
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		if text == "" {
+			text = target
+		}
+		return fmt.Sprintf("[%s](%s)", text, target)
+	}
+	uid := scope.resolveDoc(target)
+	if text == "" {
+		text = newReference(uid, "").Name
+	}
+	return fmt.Sprintf(`<xref uid="%s">%s</xref>`, uid, text)
+}
+
+// text returns all of the text of d that can contain inline tags, in the
+// order it appears in the source.
+func (d *docblock) text() string {
+	parts := []string{d.Description, d.LongDescription}
+	for _, t := range d.Tags {
+		parts = append(parts, t.Description)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// inlineTarget is the target and text of an inline tag.
+type inlineTarget struct {
+	target, text string
+}
+
+// sourceTargets returns the inline tags of the docblock at the given line of
+// the source lines. The line is either the first line of the docblock or of
+// the element it documents.
+func sourceTargets(lines []string, line int) []inlineTarget {
+	start := line - 1
+	if start >= len(lines) {
+		return nil
+	}
+	for start >= 0 && !strings.Contains(lines[start], "/**") {
+		start--
+	}
+	if start < 0 {
+		return nil
+	}
+	var b strings.Builder
+	for _, l := range lines[start:] {
+		end := strings.Index(l, "*/")
+		if end >= 0 {
+			l = l[:end]
+		}
+		l = strings.TrimSpace(l)
+		l = strings.TrimPrefix(l, "/**")
+		l = strings.TrimPrefix(l, "*")
+		b.WriteString(l)
+		b.WriteString("\n")
+		if end >= 0 {
+			break
+		}
+	}
+	var targets []inlineTarget
+	for _, m := range inlineTag.FindAllStringSubmatch(b.String(), -1) {
+		targets = append(targets, inlineTarget{target: m[2], text: collapseSpace(m[3])})
+	}
+	return targets
+}
+
+// classDocblock is a docblock and the UID of the class, interface, or trait
+// it belongs to, if any.
+type classDocblock struct {
+	*docblock
+	class string
+}
+
+// docblocks returns the docblocks of the elements declared in f.
+func (f *file) docblocks() []classDocblock {
+	var ds []classDocblock
+	class := ""
+	add := func(d *docblock) {
+		if d != nil {
+			ds = append(ds, classDocblock{d, class})
+		}
+	}
+	addMembers := func(props []property, methods []method, consts []constant) {
+		for _, p := range props {
+			add(p.Docblock)
+		}
+		for _, m := range methods {
+			add(m.Docblock)
+		}
+		for _, c := range consts {
+			add(c.Docblock)
+		}
+	}
+	if c := f.Class; c != nil {
+		class = c.FullName
+		add(c.Docblock)
+		addMembers(c.Properties, c.Methods, c.Constants)
+	}
+	if i := f.Interface; i != nil {
+		class = i.FullName
+		add(i.Docblock)
+		addMembers(nil, i.Methods, i.Constants)
+	}
+	if t := f.Trait; t != nil {
+		class = t.FullName
+		add(t.Docblock)
+		addMembers(t.Properties, t.Methods, nil)
+	}
+	class = ""
+	addMembers(nil, nil, f.Constants)
+	for _, fun := range f.Functions {
+		add(fun.Docblock)
+	}
+	return ds
+}
+
+// copyDocblocks returns a copy of f with its own copies of the docblocks of
+// the elements declared in f, so they can be rewritten without changing f.
+func copyDocblocks(f file) file {
+	copyDoc := func(d *docblock) *docblock {
+		if d == nil {
+			return nil
+		}
+		c := *d
+		c.Tags = append([]tag(nil), d.Tags...)
+		return &c
+	}
+	copyProps := func(props []property) []property {
+		props = append([]property(nil), props...)
+		for i := range props {
+			props[i].Docblock = copyDoc(props[i].Docblock)
+		}
+		return props
+	}
+	copyMethods := func(methods []method) []method {
+		methods = append([]method(nil), methods...)
+		for i := range methods {
+			methods[i].Docblock = copyDoc(methods[i].Docblock)
+		}
+		return methods
+	}
+	copyConsts := func(consts []constant) []constant {
+		consts = append([]constant(nil), consts...)
+		for i := range consts {
+			consts[i].Docblock = copyDoc(consts[i].Docblock)
+		}
+		return consts
+	}
+	if f.Class != nil {
+		c := *f.Class
+		c.Docblock = copyDoc(c.Docblock)
+		c.Properties = copyProps(c.Properties)
+		c.Methods = copyMethods(c.Methods)
+		c.Constants = copyConsts(c.Constants)
+		f.Class = &c
+	}
+	if f.Interface != nil {
+		i := *f.Interface
+		i.Docblock = copyDoc(i.Docblock)
+		i.Methods = copyMethods(i.Methods)
+		i.Constants = copyConsts(i.Constants)
+		f.Interface = &i
+	}
+	if f.Trait != nil {
+		t := *f.Trait
+		t.Docblock = copyDoc(t.Docblock)
+		t.Properties = copyProps(t.Properties)
+		t.Methods = copyMethods(t.Methods)
+		f.Trait = &t
+	}
+	f.Constants = copyConsts(f.Constants)
+	f.Functions = append([]fn(nil), f.Functions...)
+	for i := range f.Functions {
+		f.Functions[i].Docblock = copyDoc(f.Functions[i].Docblock)
+	}
+	return f
+}
+
+// fileNamespace returns the namespace of the elements declared in f.
+func fileNamespace(f *file) string {
+	switch {
+	case f.Class != nil:
+		return namespaceOf(f.Class.FullName)
+	case f.Interface != nil:
+		return namespaceOf(f.Interface.FullName)
+	case f.Trait != nil:
+		return namespaceOf(f.Trait.FullName)
+	case len(f.Constants) > 0:
+		return f.Constants[0].Namespace
+	case len(f.Functions) > 0:
+		return f.Functions[0].Namespace
+	}
+	return ""
+}
+
+// nameScope is the namespace, use imports, and class names are resolved in.
+type nameScope struct {
+	namespace string
+	// imports maps lower case aliases to the names they import.
+	imports map[string]string
+	// class is the UID of the class, interface, or trait that self, static,
+	// $this, and members without a type, like "foo()", refer to. Optional.
+	class string
+}
+
+// fileScope returns the scope of the first namespace declared in the PHP
+// source src.
+func fileScope(src string) nameScope {
+	toks := phpTokens(src)
+	s := nameScope{imports: map[string]string{}}
+	depth, nsDepth := 0, 0
+	seenNamespace := false
+	for i := 0; i < len(toks); i++ {
+		switch tok := toks[i]; {
+		case tok == "{":
+			depth++
+		case tok == "}":
+			depth--
+		case depth != nsDepth:
+		case strings.EqualFold(tok, "namespace") && i+1 < len(toks) && (isName(toks[i+1]) || toks[i+1] == "{"):
+			if seenNamespace {
+				return s
+			}
+			seenNamespace = true
+			if isName(toks[i+1]) {
+				i++
+				s.namespace = "\\" + strings.TrimPrefix(toks[i], "\\")
+			}
+			if i+1 < len(toks) && toks[i+1] == "{" {
+				nsDepth = 1
+			}
+		case strings.EqualFold(tok, "use"):
+			i = parseImports(toks, i+1, s.imports)
+		}
+	}
+	return s
+}
+
+// resolveDoc resolves the name of a structural element written in
+// documentation, like "Image", "Image::annotate()", "self::annotate()", or
+// "Google\Cloud\Vision\Image", into a UID. Unlike in code, qualified names
+// are taken to be fully qualified.
+func (s nameScope) resolveDoc(name string) string {
+	member := ""
+	if i := strings.Index(name, "::"); i >= 0 {
+		name, member = name[:i], name[i:]
+	}
+	if s.class != "" {
+		switch {
+		case strings.EqualFold(name, "self") || strings.EqualFold(name, "static") || name == "$this":
+			return s.class + member
+		case member == "" && (strings.HasSuffix(name, "()") || strings.HasPrefix(name, "$")) && !strings.Contains(name, "\\"):
+			// A method or property of the class, like "foo()" or "$foo".
+			return s.class + "::" + name
+		}
+	}
+	if strings.HasPrefix(name, "\\") {
+		return name + member
+	}
+	first, rest := name, ""
+	if i := strings.Index(name, "\\"); i >= 0 {
+		first, rest = name[:i], name[i:]
+	}
+	if full, ok := s.imports[strings.ToLower(first)]; ok {
+		return full + rest + member
+	}
+	if rest != "" {
+		return "\\" + name + member
+	}
+	return s.namespace + "\\" + name + member
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRewriteInlineTags(t *testing.T) {
+	src := `<?php
+namespace Foo\Annotation;
+
+use Foo\VisionClient;
+use Foo\Image as Img;
+
+/**
+ * Created by {@see VisionClient::annotate()} and
+ * {@see Img the image}.
+ *
+ * See {@link https://example.com/docs the docs}.
+ */
+class Entity
+{
+    /**
+     * @param array $info {@see \Foo\Info}.
+     */
+    public function __construct(array $info)
+    {
+    }
+}
+`
+	f := &file{
+		Path: "src/Annotation/Entity.php",
+		Class: &class{
+			Name:     "Entity",
+			FullName: `\Foo\Annotation\Entity`,
+			Docblock: &docblock{
+				Line:            7,
+				Description:     "Created by {@see} and {@see}.",
+				LongDescription: "See {@link https://example.com/docs the docs}.",
+			},
+			Methods: []method{{
+				Name:     "__construct",
+				FullName: `\Foo\Annotation\Entity::__construct()`,
+				Docblock: &docblock{
+					Line: 15,
+					Tags: []tag{{Name: "param", Variable: "info", Description: "{@see}."}},
+				},
+			}},
+		},
+	}
+	rewriteInlineTags(f, src)
+
+	d := f.Class.Docblock
+	if want := `Created by <xref uid="\Foo\VisionClient::annotate()">annotate()</xref> and <xref uid="\Foo\Image">the image</xref>.`; d.Description != want {
+		t.Errorf("got description %q, want %q", d.Description, want)
+	}
+	if want := "See [the docs](https://example.com/docs)."; d.LongDescription != want {
+		t.Errorf("got long description %q, want %q", d.LongDescription, want)
+	}
+	if got, want := f.Class.Methods[0].Docblock.Tags[0].Description, `<xref uid="\Foo\Info">Info</xref>.`; got != want {
+		t.Errorf("got param description %q, want %q", got, want)
+	}
+}
+
+func TestRewriteInlineTagsWithoutSource(t *testing.T) {
+	f := &file{
+		Class: &class{
+			FullName: `\Foo\Entity`,
+			Docblock: &docblock{
+				Description: "Uses {@see Image}, {@see Google\\Cloud\\Vision\\Image}, {@link https://example.com}, and a {@see} object.",
+			},
+		},
+	}
+	rewriteInlineTags(f, "")
+	want := `Uses <xref uid="\Foo\Image">Image</xref>, <xref uid="\Google\Cloud\Vision\Image">Image</xref>, [https://example.com](https://example.com), and a object.`
+	if got := f.Class.Docblock.Description; got != want {
+		t.Errorf("got description %q, want %q", got, want)
+	}
+}
+
+func TestRewriteInlineTagsClassScope(t *testing.T) {
+	f := &file{
+		Class: &class{
+			FullName: `\Foo\Entity`,
+			Docblock: &docblock{
+				Description: "See {@see self::annotate()}, {@see static::$info}, {@see $this}, {@see annotate()}, and {@see $info}.",
+			},
+		},
+		Functions: []fn{{
+			Namespace: `\Foo`,
+			FullName:  `\Foo\annotate()`,
+			Docblock:  &docblock{Description: "See {@see detect()}."},
+		}},
+	}
+	rewriteInlineTags(f, "")
+	want := `See <xref uid="\Foo\Entity::annotate()">annotate()</xref>, <xref uid="\Foo\Entity::$info">$info</xref>, <xref uid="\Foo\Entity">Entity</xref>, <xref uid="\Foo\Entity::annotate()">annotate()</xref>, and <xref uid="\Foo\Entity::$info">$info</xref>.`
+	if got := f.Class.Docblock.Description; got != want {
+		t.Errorf("got description %q, want %q", got, want)
+	}
+	if got, want := f.Functions[0].Docblock.Description, `See <xref uid="\Foo\detect()">detect()</xref>.`; got != want {
+		t.Errorf("got function description %q, want %q", got, want)
+	}
+}
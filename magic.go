@@ -0,0 +1,194 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+)
+
+// magicMethods returns an item for every method documented by a @method tag
+// in d, the docblock of the type parent. Methods in declared are skipped,
+// since the real declaration documents them better.
+func magicMethods(d *docblock, parent string, declared map[string]bool) []*item {
+	var items []*item
+	for _, t := range d.tags("method") {
+		if t.MethodName == "" || declared[strings.ToLower(t.MethodName)] {
+			continue
+		}
+		md, examples := magicDocblock(t)
+		m := method{
+			Visibility: "public",
+			Name:       t.MethodName,
+			FullName:   parent + "::" + t.MethodName + "()",
+			Docblock:   md,
+		}
+		i := methodItem(m, parent)
+		i.Examples = examples
+		items = append(items, i)
+	}
+	return items
+}
+
+// magicDocblock parses the description of the @method tag t. The
+// description is either a plain summary or a braced block, like:
+//
+//	{
+//	    Opaque entity ID.
+//
+//	    Example:
+//	    ```
+//	    echo $text->mid();
+//	    ```
+//
+//	    @see https://developers.google.com/knowledge-graph/ Knowledge Graph
+//	    @return string
+//	}
+//
+// The summary, @see, and @return tags of the block are returned as a
+// docblock, so the method can be documented like a declared one.
+func magicDocblock(t tag) (*docblock, []example) {
+	d := &docblock{}
+	desc := strings.TrimSpace(t.Description)
+	if !strings.HasPrefix(desc, "{") || !strings.HasSuffix(desc, "}") {
+		d.Description = collapseSpace(desc)
+		if t.Type != "" {
+			d.Tags = append(d.Tags, tag{Name: "return", Type: t.Type})
+		}
+		return d, nil
+	}
+	parts := splitTags(desc[1 : len(desc)-1])
+	var examples []example
+	d.Description, examples = splitTagExample(parts[0])
+	for _, part := range parts[1:] {
+		name, rest := cut(part[1:])
+		switch name {
+		case "return":
+			typ, desc := cut(rest)
+			d.Tags = append(d.Tags, tag{Name: "return", Type: typ, Description: desc})
+		case "see":
+			link, desc := cut(rest)
+			d.Tags = append(d.Tags, tag{Name: "see", LinkOrRef: link, Description: desc})
+		}
+	}
+	return d, examples
+}
+
+// splitTags splits s before every tag, like "@return", that is not part of
+// a code block or an inline tag like "{@see Foo}".
+func splitTags(s string) []string {
+	var parts []string
+	start, depth, inCode := 0, 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "```"):
+			inCode = !inCode
+			i += 2
+		case inCode:
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			depth--
+		case s[i] == '@' && depth == 0 && (i == 0 || s[i-1] == ' ' || s[i-1] == '\n') &&
+			i+1 < len(s) && s[i+1] >= 'a' && s[i+1] <= 'z':
+			parts = append(parts, s[start:i])
+			start = i
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitTagExample splits the code block following "Example:" out of desc,
+// the description of a tag. phpDocumentor collapses the lines of tag
+// descriptions, so unlike splitExamples this does not work line by line. It
+// returns the rest of desc on a single line, and the example, if any. The
+// example is named by its snippet marker, like splitExamples does.
+func splitTagExample(desc string) (string, []example) {
+	i := strings.Index(desc, "Example:")
+	if i < 0 {
+		return collapseSpace(desc), nil
+	}
+	code := strings.TrimSpace(desc[i+len("Example:"):])
+	if !strings.HasPrefix(code, "```") {
+		return collapseSpace(desc), nil
+	}
+	code = code[len("```"):]
+	end := strings.Index(code, "```")
+	if end < 0 {
+		return collapseSpace(desc), nil
+	}
+	rest := desc[:i] + " " + code[end+len("```"):]
+	name, content := snippetName(code[:end])
+	return collapseSpace(rest), []example{{Content: content, Name: name}}
+}
+
+// cut returns the first word of s and the rest of s, without surrounding
+// whitespace.
+func cut(s string) (string, string) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	return fields[0], strings.Join(fields[1:], " ")
+}
+
+// seeAlsos returns the links documented by @see tags in d. Links to
+// structural elements use their UID.
+func seeAlsos(d *docblock) []seeAlso {
+	var links []seeAlso
+	for _, t := range d.tags("see") {
+		switch link := t.LinkOrRef; {
+		case link == "":
+			continue
+		case strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://"):
+			links = append(links, seeAlso{LinkType: "HRef", LinkID: link, AltText: t.Description})
+		default:
+			uid := "\\" + strings.TrimPrefix(link, "\\")
+			links = append(links, seeAlso{LinkType: "CRef", LinkID: uid, AltText: t.Description})
+		}
+	}
+	return links
+}
+
+// magicModes maps the tags documenting magic properties to the access they
+// allow.
+var magicModes = map[string]string{
+	"property":       "read-write",
+	"property-read":  "read-only",
+	"property-write": "write-only",
+}
+
+// magicProperties returns the properties documented by @property,
+// @property-read, and @property-write tags in d. Properties in declared are
+// skipped, since the real declaration documents them better.
+func magicProperties(d *docblock, declared map[string]bool) []docfxProperty {
+	if d == nil {
+		return nil
+	}
+	var props []docfxProperty
+	for _, t := range d.Tags {
+		mode, ok := magicModes[t.Name]
+		name := strings.TrimPrefix(t.Variable, "$")
+		if !ok || name == "" || declared[name] {
+			continue
+		}
+		props = append(props, docfxProperty{
+			Type:        t.Type,
+			Name:        name,
+			Description: t.Description,
+			Mode:        mode,
+		})
+	}
+	return props
+}
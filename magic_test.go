@@ -0,0 +1,105 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMagicMethods(t *testing.T) {
+	d := &docblock{Tags: []tag{
+		{
+			Name:        "method",
+			MethodName:  "mid",
+			Description: "{     Opaque entity ID.      Example:     ```     echo $text->mid();     ```      @see https://example.com/kg Knowledge Graph      @see \\Foo\\Bar::baz()      @return string|null The ID. }",
+		},
+		{Name: "method", MethodName: "name", Type: "string", Description: "Gets the name."},
+		{Name: "method", MethodName: "declared", Description: "Already declared."},
+	}}
+	items := magicMethods(d, `\Foo\Entity`, map[string]bool{"declared": true})
+	if len(items) != 2 {
+		t.Fatalf("magicMethods got %d items, want 2", len(items))
+	}
+
+	mid := items[0]
+	if mid.UID != `\Foo\Entity::mid()` || mid.Parent != `\Foo\Entity` || mid.Summary != "Opaque entity ID." {
+		t.Errorf("got item %+v", mid)
+	}
+	if len(mid.Examples) != 1 || mid.Examples[0].Content != "echo $text->mid();" {
+		t.Errorf("got examples %+v", mid.Examples)
+	}
+	if r := mid.Syntax.Return; r == nil || r.Type != "string|null" || r.Description != "The ID." {
+		t.Errorf("got return %+v", r)
+	}
+	if want := "public function mid(): ?string"; mid.Syntax.Content != want {
+		t.Errorf("got syntax %q, want %q", mid.Syntax.Content, want)
+	}
+	wantSee := []seeAlso{
+		{LinkType: "HRef", LinkID: "https://example.com/kg", AltText: "Knowledge Graph"},
+		{LinkType: "CRef", LinkID: `\Foo\Bar::baz()`},
+	}
+	if len(mid.SeeAlso) != len(wantSee) || mid.SeeAlso[0] != wantSee[0] || mid.SeeAlso[1] != wantSee[1] {
+		t.Errorf("got see also %+v, want %+v", mid.SeeAlso, wantSee)
+	}
+
+	name := items[1]
+	if name.Summary != "Gets the name." || name.Syntax.Return == nil || name.Syntax.Return.Type != "string" {
+		t.Errorf("got item %+v", name)
+	}
+}
+
+func TestMagicProperties(t *testing.T) {
+	d := &docblock{Tags: []tag{
+		{Name: "property", Variable: "name", Type: "string", Description: "The name."},
+		{Name: "property-read", Variable: "$id", Type: "int"},
+		{Name: "property-write", Variable: "secret", Type: "string"},
+		{Name: "property", Variable: "declared", Type: "string"},
+		{Name: "param", Variable: "ignored", Type: "string"},
+	}}
+	got := magicProperties(d, map[string]bool{"declared": true})
+	want := []docfxProperty{
+		{Type: "string", Name: "name", Description: "The name.", Mode: "read-write"},
+		{Type: "int", Name: "id", Mode: "read-only"},
+		{Type: "string", Name: "secret", Mode: "write-only"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("magicProperties got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("magicProperties got %+v, want %+v", got[i], want[i])
+		}
+	}
+}
+
+func TestMagicMethodsSnippet(t *testing.T) {
+	d := &docblock{Tags: []tag{{
+		Name:        "method",
+		MethodName:  "boundingPoly",
+		Description: "{     The bounding polygon.      Example:     ```     //[snippet=poly]     $poly = $hint->boundingPoly();     ```      @return array }",
+	}}}
+	items := magicMethods(d, `\Foo\CropHint`, nil)
+	if len(items) != 1 {
+		t.Fatalf("magicMethods got %d items, want 1", len(items))
+	}
+	want := []example{{Content: "$poly = $hint->boundingPoly();", Name: "poly"}}
+	if got := items[0].Examples; !reflect.DeepEqual(got, want) {
+		t.Errorf("got examples %+v, want %+v", got, want)
+	}
+	if got := items[0].Summary; got != "The bounding polygon." {
+		t.Errorf("got summary %q, want %q", got, "The bounding polygon.")
+	}
+}
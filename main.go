@@ -28,16 +28,14 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-// TODO: handle examples with comments like this:
-//       //[snippet=gcs]
-
-// TODO: consider generating namespace pages.
-
 func main() {
 	namespace := flag.String("namespace", "", "Required. Root namespace the docs are for. Will be the root of the TOC. Must not have a trailing \\")
 	version := flag.String("version", "", "Required. The library version the docs are for")
 	structure := flag.String("structure", "structure.xml", "Path to structure.xml file")
 	outDir := flag.String("outdir", "out", "Where to write output")
+	includeProtected := flag.Bool("include-protected", false, "Include protected members, for readers extending the library")
+	sourceDir := flag.String("source", "", "Path to the library source structure.xml was generated from, to find the traits used by each class and the targets of inline tags")
+	flattenGapic := flag.Bool("flatten-gapic", false, "Document the members clients inherit from their generated GAPIC client, and its ancestors, on the client page")
 	flag.Parse()
 
 	if *structure == "" {
@@ -72,7 +70,11 @@ func main() {
 		os.Exit(1)
 	}
 
-	pages, toc, err := transform(p, *namespace)
+	pages, toc, err := transform(p, *namespace, options{
+		includeProtected: *includeProtected,
+		sourceDir:        *sourceDir,
+		flattenGapic:     *flattenGapic,
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "unable to transform: %v", err)
 		os.Exit(1)
@@ -89,7 +91,7 @@ func main() {
 func write(outDir string, pages map[string]*page, toc tableOfContents, namespace, version string) error {
 	for path, p := range pages {
 		if path == namespace {
-			path = "index.yml"
+			path = filepath.Join(outDir, "index.yml")
 		} else {
 			// We know every path starts with namespace, or we would have errored out.
 			path = strings.TrimPrefix(path, namespace)
@@ -41,7 +41,7 @@ func TestGoldens(t *testing.T) {
 		t.Fatalf("unable to parse: %v", err)
 	}
 
-	pages, toc, err := transform(p, namespace)
+	pages, toc, err := transform(p, namespace, options{})
 	if err != nil {
 		t.Fatalf("unable to transform: %v", err)
 	}
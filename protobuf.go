@@ -0,0 +1,235 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// protobufMessage is the base class of the messages generated by protoc.
+const protobufMessage = `\Google\Protobuf\Internal\Message`
+
+// field represents a field of a protobuf message.
+type field struct {
+	// Name is the name of the field in the .proto file, which is also the
+	// key used for the field in the constructor options.
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Oneof       string `yaml:"oneof,omitempty"`
+	Repeated    bool   `yaml:"repeated,omitempty"`
+	Map         bool   `yaml:"map,omitempty"`
+}
+
+// protoField matches the comment protoc adds to the accessors and
+// properties of message fields, like
+// "Generated from protobuf field <code>repeated .foo.Bar bars = 2;</code>".
+var protoField = regexp.MustCompile(`Generated from protobuf field <code>(?:optional\s+)?(repeated\s+)?(map<[^>]*>|\S+)\s+(\w+)\s*=\s*\d+`)
+
+// isMessage reports whether the class uid is a generated protobuf message.
+func (ix *index) isMessage(uid string) bool {
+	inheritance := ix.inheritance(uid)
+	return len(inheritance) > 0 && inheritance[0] == protobufMessage
+}
+
+// messageFields returns the fields of the generated message c. It also
+// returns the UIDs of the accessors of the fields, which the fields
+// document instead.
+//
+// The fields are documented by the $data option of the constructor of c.
+// The comments protoc adds to the accessors say whether a field is repeated
+// or a map, and the properties without such a comment are oneofs.
+//
+// The members of a oneof are stored in the property of the oneof instead of
+// their own. protoc declares the members of a oneof together, and the
+// properties of the oneofs in the same order, so the members of the k-th
+// oneof are the k-th run of fields without a property. If two oneofs are
+// next to each other, their runs cannot be told apart and the oneofs of
+// their members are left out.
+func messageFields(c *class) ([]field, map[string]bool) {
+	methods := map[string]method{}
+	for _, m := range c.Methods {
+		if m.InheritedFrom == "" {
+			methods[m.Name] = m
+		}
+	}
+	props := map[string]property{}
+	var oneofs []string
+	for _, p := range c.Properties {
+		if p.InheritedFrom != "" {
+			continue
+		}
+		props[p.Name] = p
+		if protoField.FindStringSubmatch(p.Docblock.summary()) == nil {
+			oneofs = append(oneofs, p.Name)
+		}
+	}
+
+	var fields []field
+	if ctor, ok := methods["__construct"]; ok {
+		if _, shape, ok := parseArrayShape(ctor.Docblock.param("data")); ok {
+			for _, p := range shape {
+				fields = append(fields, field{Name: p.Name, Type: p.Type, Description: p.Description})
+			}
+		}
+	}
+	if fields == nil {
+		// Fall back to the fields backed by a property.
+		for _, p := range c.Properties {
+			if m := protoField.FindStringSubmatch(p.Docblock.summary()); m != nil && p.InheritedFrom == "" {
+				fields = append(fields, field{Name: m[3], Description: withoutGenerated(p.Docblock.summary())})
+			}
+		}
+	}
+
+	var runs [][]int
+	inRun := false
+	for i, f := range fields {
+		_, hasProp := props[f.Name]
+		_, hasGetter := methods["get"+protoCamel(f.Name)]
+		member := !hasProp && hasGetter
+		if member && !inRun {
+			runs = append(runs, nil)
+		}
+		if member {
+			runs[len(runs)-1] = append(runs[len(runs)-1], i)
+		}
+		inRun = member
+	}
+	if len(runs) == len(oneofs) {
+		for k, run := range runs {
+			for _, i := range run {
+				fields[i].Oneof = oneofs[k]
+			}
+		}
+	} else if len(oneofs) == 1 {
+		for _, run := range runs {
+			for _, i := range run {
+				fields[i].Oneof = oneofs[0]
+			}
+		}
+	}
+
+	accessors := map[string]bool{}
+	for i := range fields {
+		f := &fields[i]
+		camel := protoCamel(f.Name)
+		for _, prefix := range []string{"get", "set", "has", "clear"} {
+			if m, ok := methods[prefix+camel]; ok {
+				accessors[m.FullName] = true
+			}
+		}
+		getter := methods["get"+camel]
+		if r := getter.Docblock.tag("return"); f.Type == "" && r != nil {
+			f.Type = r.Type
+		}
+		d := getter.Docblock
+		if p, ok := props[f.Name]; ok {
+			d = p.Docblock
+		}
+		if m := protoField.FindStringSubmatch(d.summary()); m != nil {
+			f.Repeated = m[1] != "" && !strings.HasPrefix(m[2], "map<")
+			f.Map = strings.HasPrefix(m[2], "map<")
+		}
+		if f.Description == "" {
+			f.Description = withoutGenerated(d.summary())
+		}
+	}
+	for _, o := range oneofs {
+		if m, ok := methods["get"+protoCamel(o)]; ok {
+			accessors[m.FullName] = true
+		}
+	}
+	return fields, accessors
+}
+
+// protoCamel converts the name of a field into the CamelCase protoc uses
+// for its accessors, like "image_context" to "ImageContext".
+func protoCamel(name string) string {
+	var b strings.Builder
+	capNext := true
+	for _, r := range name {
+		switch {
+		case unicode.IsLower(r):
+			if capNext {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			capNext = false
+		case unicode.IsUpper(r):
+			b.WriteRune(r)
+			capNext = false
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+			capNext = true
+		default:
+			capNext = true
+		}
+	}
+	return b.String()
+}
+
+// enumValue represents a value of a protobuf enum.
+type enumValue struct {
+	Name        string `yaml:"name"`
+	Value       int    `yaml:"value"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// enumValues returns the values of c, in order, if c is a generated protobuf
+// enum: a class with an integer constant per value, and static name() and
+// value() methods to convert between them.
+func enumValues(c *class) ([]enumValue, bool) {
+	if c.Extends != "" || len(c.Constants) == 0 {
+		return nil, false
+	}
+	helpers := 0
+	for _, m := range c.Methods {
+		if m.Static && (m.Name == "name" || m.Name == "value") {
+			helpers++
+		}
+	}
+	if helpers != 2 {
+		return nil, false
+	}
+	var values []enumValue
+	for _, k := range c.Constants {
+		v, err := strconv.Atoi(k.Value)
+		if err != nil {
+			return nil, false
+		}
+		values = append(values, enumValue{
+			Name:        k.Name,
+			Value:       v,
+			Description: withoutGenerated(k.Docblock.summary()),
+		})
+	}
+	return values, true
+}
+
+// withoutGenerated removes the lines protoc adds to descriptions, like
+// "Generated from protobuf enum <code>UNKNOWN = 0;</code>", from s.
+func withoutGenerated(s string) string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if !strings.HasPrefix(l, "Generated from protobuf ") {
+			lines = append(lines, l)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMessageFields(t *testing.T) {
+	fieldDoc := func(desc, decl string) *docblock {
+		return &docblock{Description: desc, LongDescription: "Generated from protobuf field <code>" + decl + "</code>"}
+	}
+	c := &class{
+		FullName: `\Foo\Product`,
+		Extends:  protobufMessage,
+		Properties: []property{
+			{Name: "labels", Docblock: fieldDoc("The labels.", "map<string, string> labels = 1;")},
+			{Name: "tags", Docblock: fieldDoc("The tags.", "repeated string tags = 2;")},
+			{Name: "source"},
+		},
+		Methods: []method{
+			{
+				Name:     "__construct",
+				FullName: `\Foo\Product::__construct()`,
+				Docblock: &docblock{Tags: []tag{{
+					Name:        "param",
+					Variable:    "data",
+					Description: "{ Optional. @type array|\\Google\\Protobuf\\Internal\\MapField $labels The labels. @type string[] $tags The tags. @type string $image_uri2x The URI. }",
+				}}},
+			},
+			{Name: "getLabels", FullName: `\Foo\Product::getLabels()`},
+			{Name: "setLabels", FullName: `\Foo\Product::setLabels()`},
+			{Name: "getTags", FullName: `\Foo\Product::getTags()`},
+			{Name: "getImageUri2X", FullName: `\Foo\Product::getImageUri2X()`, Docblock: fieldDoc("The URI.", "string image_uri2x = 3;")},
+			{Name: "hasImageUri2X", FullName: `\Foo\Product::hasImageUri2X()`},
+			{Name: "getSource", FullName: `\Foo\Product::getSource()`},
+			{Name: "describe", FullName: `\Foo\Product::describe()`},
+		},
+	}
+	fields, accessors := messageFields(c)
+	want := []field{
+		{Name: "labels", Type: `array|\Google\Protobuf\Internal\MapField`, Description: "The labels.", Map: true},
+		{Name: "tags", Type: "string[]", Description: "The tags.", Repeated: true},
+		{Name: "image_uri2x", Type: "string", Description: "The URI.", Oneof: "source"},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("messageFields got fields %+v, want %+v", fields, want)
+	}
+	for _, m := range c.Methods {
+		isAccessor := m.Name != "__construct" && m.Name != "describe"
+		if accessors[m.FullName] != isAccessor {
+			t.Errorf("messageFields got accessor %v for %s, want %v", accessors[m.FullName], m.Name, isAccessor)
+		}
+	}
+}
+
+func TestEnumValues(t *testing.T) {
+	helpers := []method{{Name: "name", Static: true}, {Name: "value", Static: true}}
+	enum := &class{
+		Constants: []constant{
+			{Name: "UNKNOWN", Value: "0", Docblock: &docblock{
+				Description:     "Unknown.",
+				LongDescription: "Generated from protobuf enum <code>UNKNOWN = 0;</code>",
+			}},
+			{Name: "LIKELY", Value: "4"},
+		},
+		Methods: helpers,
+	}
+	want := []enumValue{{Name: "UNKNOWN", Value: 0, Description: "Unknown."}, {Name: "LIKELY", Value: 4}}
+	if got, ok := enumValues(enum); !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("enumValues got %+v, %v, want %+v, true", got, ok, want)
+	}
+
+	for _, c := range []*class{
+		{Constants: enum.Constants},
+		{Constants: []constant{{Name: "NAME", Value: "'name'"}}, Methods: helpers},
+		{Constants: enum.Constants, Methods: helpers, Extends: `\Foo\Base`},
+	} {
+		if _, ok := enumValues(c); ok {
+			t.Errorf("enumValues(%+v) got ok true, want false", c)
+		}
+	}
+}
+
+func TestMessageFieldsOneofs(t *testing.T) {
+	fieldDoc := func(desc, long, decl string) *docblock {
+		return &docblock{Description: desc, LongDescription: long + "\n\nGenerated from protobuf field <code>" + decl + "</code>"}
+	}
+	getter := func(name string, d *docblock) method {
+		return method{Name: name, FullName: `\Foo\Request::` + name + "()", Docblock: d}
+	}
+	c := &class{
+		FullName: `\Foo\Request`,
+		Extends:  protobufMessage,
+		Properties: []property{
+			{Name: "parent", Docblock: fieldDoc("The parent.", "Format is `projects/ID`.", "string parent = 3;")},
+			{Name: "source"},
+			{Name: "target"},
+		},
+		Methods: []method{
+			{
+				Name: "__construct",
+				Docblock: &docblock{Tags: []tag{{
+					Name:        "param",
+					Variable:    "data",
+					Description: "{ Optional. @type string $gcs_source @type string $inline_source The inline source. @type string $parent The parent. @type string $product_set The product set. }",
+				}}},
+			},
+			getter("getGcsSource", fieldDoc("The GCS source.", "Must start with `gs://`.", "string gcs_source = 1;")),
+			getter("getInlineSource", fieldDoc("The inline source.", "", "bytes inline_source = 2;")),
+			getter("getParent", fieldDoc("The parent.", "Format is `projects/ID`.", "string parent = 3;")),
+			getter("getProductSet", fieldDoc("The product set.", "", "string product_set = 4;")),
+			getter("getSource", nil),
+			getter("getTarget", nil),
+		},
+	}
+	fields, _ := messageFields(c)
+	want := []field{
+		{Name: "gcs_source", Type: "string", Description: "The GCS source.\n\nMust start with `gs://`.", Oneof: "source"},
+		{Name: "inline_source", Type: "string", Description: "The inline source.", Oneof: "source"},
+		{Name: "parent", Type: "string", Description: "The parent."},
+		{Name: "product_set", Type: "string", Description: "The product set.", Oneof: "target"},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("messageFields got fields %+v, want %+v", fields, want)
+	}
+
+	// Without parent between them, the oneofs cannot be told apart.
+	c.Properties = c.Properties[1:]
+	c.Methods = append(c.Methods[:3], c.Methods[4:]...)
+	c.Methods[0].Docblock.Tags[0].Description = "{ Optional. @type string $gcs_source @type string $inline_source @type string $product_set }"
+	fields, _ = messageFields(c)
+	for _, f := range fields {
+		if f.Oneof != "" {
+			t.Errorf("messageFields got oneof %q for %s of adjacent oneofs, want none", f.Oneof, f.Name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+)
+
+// methodSignature returns the PHP declaration of m, like
+// "public static function foo(string $bar, ?int $baz = null): Qux".
+func methodSignature(m method) string {
+	var modifiers []string
+	if m.Abstract {
+		modifiers = append(modifiers, "abstract")
+	}
+	if m.Final {
+		modifiers = append(modifiers, "final")
+	}
+	if m.Visibility != "" {
+		modifiers = append(modifiers, m.Visibility)
+	}
+	if m.Static {
+		modifiers = append(modifiers, "static")
+	}
+	return signature(modifiers, m.Name, m.Arguments, m.Docblock)
+}
+
+// functionSignature returns the PHP declaration of f, like
+// "function foo(string $bar): Qux".
+func functionSignature(f fn) string {
+	return signature(nil, f.Name, f.Arguments, f.Docblock)
+}
+
+// propertySignature returns the PHP declaration of p, like
+// "protected array $info".
+func propertySignature(p property) string {
+	s := ""
+	if p.Visibility != "" {
+		s += p.Visibility + " "
+	}
+	if v := p.Docblock.tag("var"); v != nil {
+		if t := typeHint(v.Type, false); t != "" {
+			s += t + " "
+		}
+	}
+	return s + "$" + p.Name
+}
+
+func signature(modifiers []string, name string, args []argument, d *docblock) string {
+	var b strings.Builder
+	for _, m := range modifiers {
+		b.WriteString(m)
+		b.WriteString(" ")
+	}
+	b.WriteString("function ")
+	b.WriteString(name)
+	b.WriteString("(")
+	for i, a := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(argumentSignature(a))
+	}
+	b.WriteString(")")
+	if r := d.tag("return"); r != nil {
+		if t := typeHint(r.Type, false); t != "" {
+			b.WriteString(": ")
+			b.WriteString(t)
+		}
+	}
+	return b.String()
+}
+
+// argumentSignature returns the declaration of a, like
+// "?string &$name = null" or "string ...$names".
+func argumentSignature(a argument) string {
+	s := ""
+	def := a.defaultValue()
+	if t := typeHint(a.Type, def == "null"); t != "" {
+		s += t + " "
+	}
+	if a.ByReference {
+		s += "&"
+	}
+	if a.Variadic {
+		s += "..."
+	}
+	s += "$" + a.Name
+	if def != "" {
+		s += " = " + def
+	}
+	return s
+}
+
+// shortName returns the name of the class uid without its namespace.
+func shortName(uid string) string {
+	return uid[strings.LastIndex(uid, "\\")+1:]
+}
+
+// pseudoTypes are the PHPDoc types that cannot be written as type hints.
+var pseudoTypes = map[string]bool{
+	"resource": true,
+	"scalar":   true,
+	"number":   true,
+	"numeric":  true,
+	"callback": true,
+}
+
+// typeHint converts the PHPDoc type t into the type hint a reader would
+// write in code. Classes are referred to by their short name, unless
+// several classes of t share it, and typed arrays like Foo[] become array. If nullable is true, the type is made
+// nullable. If t cannot be written as a type hint, like "resource|string",
+// typeHint returns "".
+func typeHint(t string, nullable bool) string {
+	var parts []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(t, "|") {
+		switch {
+		case part == "":
+			continue
+		case pseudoTypes[part]:
+			return ""
+		case part == "$this":
+			part = "static"
+		case strings.HasSuffix(part, "[]"):
+			part = "array"
+		}
+		if part == "null" {
+			nullable = true
+			continue
+		}
+		if !seen[part] {
+			seen[part] = true
+			parts = append(parts, part)
+		}
+	}
+	shortNames := map[string]int{}
+	for _, part := range parts {
+		shortNames[shortName(part)]++
+	}
+	for i, part := range parts {
+		if short := shortName(part); shortNames[short] == 1 {
+			parts[i] = short
+		}
+	}
+	switch {
+	case len(parts) == 0 || (len(parts) > 1 && seen["mixed"]):
+		// mixed already includes every other type and null.
+		return ""
+	case !nullable || parts[0] == "mixed":
+		return strings.Join(parts, "|")
+	case len(parts) == 1:
+		return "?" + parts[0]
+	default:
+		return strings.Join(parts, "|") + "|null"
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestTypeHint(t *testing.T) {
+	tests := []struct {
+		t        string
+		nullable bool
+		want     string
+	}{
+		{t: "string", want: "string"},
+		{t: `\Foo\Bar`, want: "Bar"},
+		{t: `\Foo\Bar|\Foo\Bar`, want: "Bar"},
+		{t: `\A\Bar|\B\Bar`, want: `\A\Bar|\B\Bar`},
+		{t: `\A\Bar|\B\Baz|null`, want: "Bar|Baz|null"},
+		{t: `\Foo\Bar[]`, want: "array"},
+		{t: `\Foo\Bar[]|string[]`, want: "array"},
+		{t: "$this", want: "static"},
+		{t: "string", nullable: true, want: "?string"},
+		{t: "string|null", want: "?string"},
+		{t: "null|string", want: "?string"},
+		{t: "int|string", want: "int|string"},
+		{t: "int|string|null", want: "int|string|null"},
+		{t: "int|string", nullable: true, want: "int|string|null"},
+		{t: "mixed", want: "mixed"},
+		{t: "mixed", nullable: true, want: "mixed"},
+		{t: "mixed|string", want: ""},
+		{t: "resource", want: ""},
+		{t: `resource|string|\Foo\Image`, want: ""},
+		{t: "callback", want: ""},
+		{t: "null", want: ""},
+		{t: "", want: ""},
+	}
+	for _, test := range tests {
+		if got := typeHint(test.t, test.nullable); got != test.want {
+			t.Errorf("typeHint(%q, %v) got %q, want %q", test.t, test.nullable, got, test.want)
+		}
+	}
+}
+
+func TestArgumentSignature(t *testing.T) {
+	tests := []struct {
+		a    argument
+		want string
+	}{
+		{argument{Name: "name", Type: "string"}, "string $name"},
+		{argument{Name: "name"}, "$name"},
+		{argument{Name: "name", Type: "string", Default: "NULL"}, "?string $name = null"},
+		{argument{Name: "name", Type: "string|null", Default: "null"}, "?string $name = null"},
+		{argument{Name: "options", Type: "array", Default: "[]"}, "array $options = []"},
+		{argument{Name: "matches", Type: "array", ByReference: true}, "array &$matches"},
+		{argument{Name: "names", Type: "string", Variadic: true}, "string ...$names"},
+		{argument{Name: "args", ByReference: true, Variadic: true}, "&...$args"},
+		{argument{Name: "input", Type: "resource|string"}, "$input"},
+		{argument{Name: "input", Type: "resource", Default: "null"}, "$input = null"},
+	}
+	for _, test := range tests {
+		if got := argumentSignature(test.a); got != test.want {
+			t.Errorf("argumentSignature(%+v) got %q, want %q", test.a, got, test.want)
+		}
+	}
+}
+
+func TestMethodSignature(t *testing.T) {
+	returns := func(typ string) *docblock {
+		return &docblock{Tags: []tag{{Name: "return", Type: typ}}}
+	}
+	tests := []struct {
+		m    method
+		want string
+	}{
+		{
+			m:    method{Name: "foo", Visibility: "public"},
+			want: "public function foo()",
+		},
+		{
+			m: method{
+				Name:       "create",
+				Visibility: "public",
+				Static:     true,
+				Final:      true,
+				Arguments:  []argument{{Name: "bar", Type: "string"}, {Name: "baz", Type: "int", Default: "null"}},
+				Docblock:   returns(`\Foo\Qux`),
+			},
+			want: "final public static function create(string $bar, ?int $baz = null): Qux",
+		},
+		{
+			m:    method{Name: "run", Visibility: "protected", Abstract: true, Docblock: returns("void")},
+			want: "abstract protected function run(): void",
+		},
+		{
+			m:    method{Name: "find", Visibility: "public", Docblock: returns(`\Foo\Bar|null`)},
+			want: "public function find(): ?Bar",
+		},
+		{
+			m:    method{Name: "read", Visibility: "public", Docblock: returns("resource|string")},
+			want: "public function read()",
+		},
+		{
+			m:    method{Name: "with", Visibility: "public", Docblock: returns("$this")},
+			want: "public function with(): static",
+		},
+	}
+	for _, test := range tests {
+		if got := methodSignature(test.m); got != test.want {
+			t.Errorf("methodSignature(%s) got %q, want %q", test.m.Name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// snippetMarker matches the comments google-cloud-php uses to name the
+// snippets its tests run, like "//[snippet=gcs]".
+var snippetMarker = regexp.MustCompile(`^//\s*\[snippet=([^\]]*)\]`)
+
+// snippetName splits the snippet marker off the start of code. It returns
+// the name of the snippet, or "" if code does not start with a marker, and
+// the rest of code.
+func snippetName(code string) (string, string) {
+	code = strings.TrimSpace(code)
+	m := snippetMarker.FindStringSubmatchIndex(code)
+	if m == nil {
+		return "", code
+	}
+	return code[m[2]:m[3]], strings.TrimSpace(code[m[1]:])
+}
+
+// splitExamples splits the code examples out of the Markdown description s.
+// Examples are the fenced code blocks following an "Example:" line, which is
+// removed with them, and any code block starting with a snippet marker. The
+// marker names the example and is removed from its content.
+//
+// splitExamples returns s without the examples, and the examples.
+func splitExamples(s string) (string, []example) {
+	lines := strings.Split(s, "\n")
+	var kept []string
+	var examples []example
+	inExample := false // Whether the last text was an "Example:" line.
+	exampleAt := 0     // The index in kept of the last "Example:" line.
+	dropBlank := false // Whether to drop blank lines after removed text.
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "":
+			if dropBlank {
+				continue
+			}
+		case trimmed == "Example:":
+			// The line is only dropped once an example follows it.
+			inExample, exampleAt, dropBlank = true, len(kept), false
+		case strings.HasPrefix(trimmed, "```"):
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "```" {
+				end++
+			}
+			if end == len(lines) {
+				// Unterminated code block. Leave the rest of s as is.
+				kept = append(kept, lines[i:]...)
+				i = end
+				continue
+			}
+			code := lines[i+1 : end]
+			name := ""
+			if len(code) > 0 {
+				// The marker is a line of its own.
+				if n, rest := snippetName(code[0]); n != "" && rest == "" {
+					name, code = n, code[1:]
+				}
+			}
+			if inExample || name != "" {
+				if inExample {
+					kept = kept[:exampleAt]
+				}
+				examples = append(examples, example{
+					Content: strings.Trim(strings.Join(code, "\n"), "\n"),
+					Name:    name,
+				})
+				dropBlank = true
+			} else {
+				kept = append(kept, lines[i:end+1]...)
+				dropBlank = false
+			}
+			i = end
+			continue
+		default:
+			inExample, dropBlank = false, false
+		}
+		kept = append(kept, lines[i])
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n "), examples
+}
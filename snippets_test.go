@@ -0,0 +1,56 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSplitExamples(t *testing.T) {
+	s := "Some text.\n\n" +
+		"```\nnot an example\n```\n\n" +
+		"Example:\n```\n//[snippet=default]\n$a = 1;\n\n$b = 2;\n```\n\n" +
+		"```php\n$c = 3;\n```\n\n" +
+		"More text.\n\n" +
+		"```\n// [snippet=named]\n$d = 4;\n```\n"
+	got, examples := splitExamples(s)
+	want := "Some text.\n\n```\nnot an example\n```\n\nMore text."
+	if got != want {
+		t.Errorf("splitExamples got text %q, want %q", got, want)
+	}
+	wantExamples := []example{
+		{Name: "default", Content: "$a = 1;\n\n$b = 2;"},
+		{Content: "$c = 3;"},
+		{Name: "named", Content: "$d = 4;"},
+	}
+	if len(examples) != len(wantExamples) {
+		t.Fatalf("splitExamples got examples %+v, want %+v", examples, wantExamples)
+	}
+	for i := range wantExamples {
+		if examples[i] != wantExamples[i] {
+			t.Errorf("splitExamples got example %+v, want %+v", examples[i], wantExamples[i])
+		}
+	}
+
+	unterminated := "Example:\n```\n$a = 1;"
+	if got, examples := splitExamples(unterminated); got != unterminated || len(examples) != 0 {
+		t.Errorf("splitExamples(%q) got %q, %+v", unterminated, got, examples)
+	}
+
+	prose := "Intro.\n\nExample:\nNot code, but prose."
+	if got, examples := splitExamples(prose); got != prose || len(examples) != 0 {
+		t.Errorf("splitExamples(%q) got %q, %+v", prose, got, examples)
+	}
+}
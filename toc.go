@@ -0,0 +1,157 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// tableOfContents represents a TOC.
+type tableOfContents []*tocItem
+
+// tocItem is an item in a TOC.
+type tocItem struct {
+	UID    string     `yaml:"uid,omitempty"`
+	Name   string     `yaml:"name,omitempty"`
+	Items  []*tocItem `yaml:"items,omitempty"`
+	Href   string     `yaml:"href,omitempty"`
+	Status string     `yaml:"status,omitempty"`
+}
+
+func (t *tocItem) addItem(i *tocItem) {
+	t.Items = append(t.Items, i)
+}
+
+// tocBuilder builds a TOC that mirrors the namespace hierarchy of the
+// project.
+//
+// Namespace nodes only get a UID when something provides a page for them:
+// either a namespace page or a type with the same full name (for example,
+// the protobuf message \V1\Feature and its nested \V1\Feature\Type).
+type tocBuilder struct {
+	rootNamespace string
+	root          *tocItem
+	namespaces    map[string]*tocItem
+	// aliases groups the deprecated aliases of types, to keep them out of
+	// the way of the types they alias.
+	aliases *tocItem
+}
+
+// newTOCBuilder creates a tocBuilder rooted at rootNamespace, seeded with
+// the sub-namespaces of rootNamespace found in namespaces.
+func newTOCBuilder(rootNamespace string, namespaces []projectNamespace) *tocBuilder {
+	b := &tocBuilder{
+		rootNamespace: rootNamespace,
+		root:          &tocItem{Name: rootNamespace},
+		namespaces:    map[string]*tocItem{},
+	}
+	b.namespaces[rootNamespace] = b.root
+	if pn := findNamespace(namespaces, rootNamespace); pn != nil {
+		b.seed(pn.ProjectNamespaces)
+	}
+	return b
+}
+
+// findNamespace finds the namespace named fullName in the given namespace
+// trees.
+func findNamespace(namespaces []projectNamespace, fullName string) *projectNamespace {
+	for i, pn := range namespaces {
+		if pn.FullName == fullName {
+			return &namespaces[i]
+		}
+		if found := findNamespace(pn.ProjectNamespaces, fullName); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (b *tocBuilder) seed(namespaces []projectNamespace) {
+	for _, pn := range namespaces {
+		if inNamespace(pn.FullName, b.rootNamespace) {
+			b.namespace(pn.FullName)
+		}
+		b.seed(pn.ProjectNamespaces)
+	}
+}
+
+// namespace returns the node for the namespace ns, creating it and its
+// ancestors if needed. ns must be rootNamespace or one of its sub-namespaces.
+func (b *tocBuilder) namespace(ns string) *tocItem {
+	if t, ok := b.namespaces[ns]; ok {
+		return t
+	}
+	i := strings.LastIndex(ns, "\\")
+	parent := b.namespace(ns[:i])
+	name := ns[i+1:]
+	// Reuse the entry for a type with the same name, if there is one.
+	for _, t := range parent.Items {
+		if t.UID == ns {
+			b.namespaces[ns] = t
+			return t
+		}
+	}
+	t := &tocItem{Name: name}
+	parent.addItem(t)
+	b.namespaces[ns] = t
+	return t
+}
+
+// add adds i to the node for the namespace ns. If i is a type with the same
+// name as a namespace, i is merged into the namespace node instead.
+func (b *tocBuilder) add(ns string, i *tocItem) {
+	if t, ok := b.namespaces[i.UID]; ok {
+		t.UID = i.UID
+		t.Status = i.Status
+		return
+	}
+	b.namespace(ns).addItem(i)
+}
+
+// alias adds i, a deprecated alias of another type, to the group of
+// deprecated aliases. The alias is named by its UID relative to
+// rootNamespace, since aliases from every namespace share the group.
+func (b *tocBuilder) alias(i *tocItem) {
+	if b.aliases == nil {
+		b.aliases = &tocItem{Name: "Deprecated aliases"}
+		b.root.addItem(b.aliases)
+	}
+	i.Name = strings.TrimPrefix(i.UID, b.rootNamespace+"\\")
+	b.aliases.addItem(i)
+}
+
+// toc returns the finished TOC, sorted by name and without any empty
+// namespaces.
+func (b *tocBuilder) toc() tableOfContents {
+	finish(b.root)
+	return tableOfContents{b.root}
+}
+
+// finish sorts the items of t and prunes namespace nodes that ended up
+// without a page or any items. It reports whether t should be kept.
+func finish(t *tocItem) bool {
+	items := t.Items[:0]
+	for _, i := range t.Items {
+		if finish(i) {
+			items = append(items, i)
+		}
+	}
+	t.Items = items
+	sort.Slice(t.Items, func(i, j int) bool {
+		return t.Items[i].Name < t.Items[j].Name
+	})
+	return t.UID != "" || len(t.Items) > 0
+}
@@ -0,0 +1,267 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// usedTraits returns the UIDs of the traits used by each class and trait
+// declared in the PHP source src, keyed by the UID of the user.
+//
+// phpDocumentor does not record which traits a class uses, so they are found
+// by scanning the source. Trait names are resolved against the namespace and
+// the use imports of the file.
+func usedTraits(src string) map[string][]string {
+	toks := phpTokens(src)
+	used := map[string][]string{}
+	ns := ""
+	imports := map[string]string{}
+	resolve := func(name string) string {
+		if strings.HasPrefix(name, "\\") {
+			return name
+		}
+		first := name
+		rest := ""
+		if i := strings.Index(name, "\\"); i >= 0 {
+			first, rest = name[:i], name[i:]
+		}
+		if full, ok := imports[strings.ToLower(first)]; ok {
+			return full + rest
+		}
+		return ns + "\\" + name
+	}
+
+	depth := 0
+	nsDepth := 0      // The depth of the body of the namespace.
+	user := ""        // The class or trait being declared, if any.
+	userDepth := -1   // The depth of the body of user.
+	pendingUser := "" // A declaration whose body is not open yet.
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+		switch {
+		case tok == "{":
+			depth++
+			if pendingUser != "" {
+				user, userDepth, pendingUser = pendingUser, depth, ""
+			}
+		case tok == "}":
+			if depth == userDepth {
+				user, userDepth = "", -1
+			}
+			depth--
+		case depth != nsDepth || user != "":
+			if depth == userDepth && strings.EqualFold(tok, "use") {
+				i = parseTraitUse(toks, i+1, func(name string) {
+					used[user] = append(used[user], resolve(name))
+				})
+			}
+		case strings.EqualFold(tok, "namespace") && i+1 < len(toks) && (isName(toks[i+1]) || toks[i+1] == "{"):
+			ns, nsDepth = "", 0
+			if isName(toks[i+1]) {
+				i++
+				ns = "\\" + strings.TrimPrefix(toks[i], "\\")
+			}
+			if i+1 < len(toks) && toks[i+1] == "{" {
+				nsDepth = 1
+			}
+			imports = map[string]string{}
+		case strings.EqualFold(tok, "use"):
+			i = parseImports(toks, i+1, imports)
+		case isDeclaration(toks, i):
+			i++
+			pendingUser = ns + "\\" + toks[i]
+		}
+	}
+	return used
+}
+
+// isDeclaration reports whether toks[i] starts the declaration of a class
+// or trait, as opposed to uses like Foo::class or $class.
+func isDeclaration(toks []string, i int) bool {
+	if !strings.EqualFold(toks[i], "class") && !strings.EqualFold(toks[i], "trait") {
+		return false
+	}
+	if i > 0 && (toks[i-1] == ":" || toks[i-1] == "$" || toks[i-1] == ">") {
+		return false
+	}
+	return i+1 < len(toks) && isName(toks[i+1]) &&
+		!strings.EqualFold(toks[i+1], "extends") && !strings.EqualFold(toks[i+1], "implements")
+}
+
+// parseTraitUse parses the trait use statement starting at toks[i], calling
+// add with the name of every trait. It returns the index of the terminating
+// ";" or "}".
+func parseTraitUse(toks []string, i int, add func(name string)) int {
+	for ; i < len(toks) && toks[i] != ";" && toks[i] != "{"; i++ {
+		if isName(toks[i]) {
+			add(toks[i])
+		}
+	}
+	if i < len(toks) && toks[i] == "{" {
+		// Skip the conflict resolution block.
+		for d := 0; i < len(toks); i++ {
+			if toks[i] == "{" {
+				d++
+			} else if toks[i] == "}" {
+				if d--; d == 0 {
+					break
+				}
+			}
+		}
+	}
+	return i
+}
+
+// parseImports parses the use statement starting at toks[i] into imports,
+// keyed by lower case alias. It returns the index of the terminating ";".
+// Function and constant imports are skipped.
+func parseImports(toks []string, i int, imports map[string]string) int {
+	if i < len(toks) && (strings.EqualFold(toks[i], "function") || strings.EqualFold(toks[i], "const")) {
+		for ; i < len(toks) && toks[i] != ";"; i++ {
+		}
+		return i
+	}
+	prefix := ""
+	name := ""
+	add := func(alias string) {
+		if name == "" {
+			return
+		}
+		full := "\\" + strings.TrimPrefix(prefix+name, "\\")
+		if alias == "" {
+			alias = full[strings.LastIndex(full, "\\")+1:]
+		}
+		imports[strings.ToLower(alias)] = full
+		name = ""
+	}
+	for ; i < len(toks) && toks[i] != ";"; i++ {
+		switch tok := toks[i]; {
+		case tok == "{":
+			// Group use, like "use Foo\{Bar, Baz as Qux};".
+			prefix, name = name, ""
+		case tok == "}":
+			add("")
+			prefix = ""
+		case tok == ",":
+			add("")
+		case strings.EqualFold(tok, "as") && i+1 < len(toks):
+			i++
+			add(toks[i])
+		case isName(tok):
+			name = tok
+		}
+	}
+	add("")
+	return i
+}
+
+// isName reports whether tok is a PHP name, like "Foo\Bar".
+func isName(tok string) bool {
+	return tok != "" && (tok[0] == '\\' || tok[0] == '_' || unicode.IsLetter(rune(tok[0])))
+}
+
+// phpTokens splits src into names and punctuation, skipping whitespace,
+// comments, and string literals, including heredocs and nowdocs. It is just
+// precise enough to find declarations and use statements.
+func phpTokens(src string) []string {
+	var toks []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "//") || (c == '#' && !strings.HasPrefix(src[i:], "#[")):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return toks
+			}
+			i += 2 + end + 2
+		case strings.HasPrefix(src[i:], "<<<"):
+			end := heredocEnd(src, i)
+			if end < 0 {
+				return toks
+			}
+			i = end
+		case c == '\'' || c == '"' || c == '`':
+			for i++; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case c == '\\' || c == '_' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '\\' || src[i] == '_' || src[i] >= 0x80 ||
+				unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			toks = append(toks, src[start:i])
+		case unicode.IsSpace(rune(c)):
+			i++
+		default:
+			toks = append(toks, string(c))
+			i++
+		}
+	}
+	return toks
+}
+
+// heredocEnd returns the index just past the heredoc or nowdoc starting at
+// src[i], like "<<<EOT\n...\nEOT", or -1 if it does not end. The closing
+// identifier may be indented, as allowed since PHP 7.3.
+func heredocEnd(src string, i int) int {
+	j := i + len("<<<")
+	for j < len(src) && (src[j] == ' ' || src[j] == '\t') {
+		j++
+	}
+	quote := byte(0)
+	if j < len(src) && (src[j] == '\'' || src[j] == '"') {
+		quote = src[j]
+		j++
+	}
+	start := j
+	for j < len(src) && isIdentByte(src[j]) {
+		j++
+	}
+	id := src[start:j]
+	if quote != 0 && j < len(src) && src[j] == quote {
+		j++
+	}
+	nl := strings.IndexByte(src[j:], '\n')
+	if id == "" || nl < 0 {
+		return -1
+	}
+	for pos := j + nl + 1; pos < len(src); {
+		line := src[pos:]
+		if k := strings.IndexByte(line, '\n'); k >= 0 {
+			line = line[:k]
+		}
+		body := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(body, id) && (len(body) == len(id) || !isIdentByte(body[len(id)])) {
+			return pos + len(line) - len(body) + len(id)
+		}
+		pos += len(line) + 1
+	}
+	return -1
+}
+
+// isIdentByte reports whether c can be part of a PHP identifier.
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUsedTraits(t *testing.T) {
+	src := `<?php
+namespace Google\Cloud\Vision\Annotation;
+
+use Google\Cloud\Core\{ArrayTrait, CallTrait as Caller};
+use Google\Cloud\Core\ValidateTrait;
+use function strtolower;
+
+// class Commented { use NotATrait; }
+
+/**
+ * A face. Uses {@see LikelihoodTrait}.
+ */
+class Face extends AbstractFeature
+{
+    use LikelihoodTrait;
+    use ArrayTrait, Caller {
+        Caller::call as protected;
+    }
+    use \Fully\Qualified\Trait, ValidateTrait;
+
+    private $name = 'class Fake { use NotATrait; }';
+
+    public function all()
+    {
+        $class = Face::class;
+        $fn = function () use ($class) {
+            return new class {
+                use InsideAnonymousClass;
+            };
+        };
+    }
+}
+
+trait Helpers
+{
+    use Sub\HelperTrait;
+}
+`
+	got := usedTraits(src)
+	want := map[string][]string{
+		`\Google\Cloud\Vision\Annotation\Face`: {
+			`\Google\Cloud\Vision\Annotation\LikelihoodTrait`,
+			`\Google\Cloud\Core\ArrayTrait`,
+			`\Google\Cloud\Core\CallTrait`,
+			`\Fully\Qualified\Trait`,
+			`\Google\Cloud\Core\ValidateTrait`,
+		},
+		`\Google\Cloud\Vision\Annotation\Helpers`: {
+			`\Google\Cloud\Vision\Annotation\Sub\HelperTrait`,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("usedTraits got %v, want %v", got, want)
+	}
+}
+
+func TestUsedTraitsHeredoc(t *testing.T) {
+	src := `<?php
+namespace Foo;
+
+class Template
+{
+    use First;
+
+    const HEADER = <<<EOT
+        class Fake {
+            use NotATrait;
+        EOT;
+
+    private $body = <<<'SQL'
+    } use AlsoNotATrait; {
+    SQL;
+
+    private $shift = 1 << 2;
+
+    use Second;
+}
+
+class After
+{
+    use Third;
+}
+`
+	got := usedTraits(src)
+	want := map[string][]string{
+		`\Foo\Template`: {`\Foo\First`, `\Foo\Second`},
+		`\Foo\After`:    {`\Foo\Third`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("usedTraits got %v, want %v", got, want)
+	}
+}
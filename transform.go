@@ -17,282 +17,895 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 )
 
+// options configures transform.
+type options struct {
+	// includeProtected documents protected members, for readers extending
+	// the library. Private members are never documented.
+	includeProtected bool
+	// sourceDir is the root of the PHP source the project was extracted
+	// from, for the details phpDocumentor does not record, like the traits
+	// used by each class and the targets of some inline tags. Optional.
+	sourceDir string
+	// flattenGapic documents the members classes inherit from the GAPIC
+	// client they extend, and from the ancestors of the GAPIC client, as
+	// members of the class, so the public client page documents the whole
+	// client. Members of traits and of classes outside the project are
+	// still only listed as inherited members.
+	flattenGapic bool
+}
+
+// visible reports whether a member with the given visibility and docblock
+// should be documented. @access and @internal tags take precedence over
+// the declared visibility.
+func (o options) visible(visibility string, d *docblock) bool {
+	if d.tag("internal") != nil {
+		return false
+	}
+	if a := d.tag("access"); a != nil && a.Description != "" {
+		visibility = a.Description
+	}
+	switch visibility {
+	case "", "public":
+		return true
+	case "protected":
+		return o.includeProtected
+	}
+	return false
+}
+
+// visibleMethods returns the methods documented under o. Only those take
+// precedence over the magic methods with the same name: a private method
+// proxied by __call does not document the public magic method.
+func (o options) visibleMethods(methods []method) []method {
+	var visible []method
+	for _, m := range methods {
+		if o.visible(m.Visibility, m.Docblock) {
+			visible = append(visible, m)
+		}
+	}
+	return visible
+}
+
+// visibleProperties returns the properties documented under o. Only those
+// take precedence over the magic properties with the same name: a private
+// property read through __get does not document the magic property.
+func (o options) visibleProperties(props []property) []property {
+	var visible []property
+	for _, p := range props {
+		if o.visible(p.Visibility, p.Docblock) {
+			visible = append(visible, p)
+		}
+	}
+	return visible
+}
+
 // transform translates from the XML input types into YAML output types.
-func transform(p *project, rootNamespace string) (map[string]*page, tableOfContents, error) {
+func transform(p *project, rootNamespace string, opts options) (map[string]*page, tableOfContents, error) {
 	pages := map[string]*page{}
 	// TODO: consider grouping by namespace and by deprecation status.
-	// TODO: cross references.
-	// TODO: visibility.
-	tocRoot := &tocItem{Name: rootNamespace}
+	toc := newTOCBuilder(rootNamespace, p.ProjectNamespaces)
+	// types holds references to the classes, interfaces, and traits of
+	// each namespace.
+	types := map[string][]*reference{}
+	// members holds the file-level constants and functions of each
+	// namespace.
+	members := map[string][]*item{}
+
+	// sources holds the PHP source of each file, by path, if available.
+	sources := map[string]string{}
+	// Inline tags are rewritten in copies of the files of p, to leave p
+	// as is.
+	files := make([]file, len(p.Files))
+	for i, f := range p.Files {
+		files[i] = f
+		if isTest(f) {
+			continue
+		}
+		if opts.sourceDir != "" {
+			src, err := os.ReadFile(filepath.Join(opts.sourceDir, f.Path))
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to read source: %v", err)
+			}
+			sources[f.Path] = string(src)
+		}
+		files[i] = copyDocblocks(f)
+		rewriteInlineTags(&files[i], sources[f.Path])
+	}
+	p = &project{Files: files, Name: p.Name, ProjectNamespaces: p.ProjectNamespaces}
+
+	ix := newIndex(p)
 
 	for _, f := range p.Files {
-		if strings.HasPrefix(f.Path, "tests") {
+		if isTest(f) {
 			continue
 		}
 
-		if len(f.Constants) > 0 {
-			fmt.Fprintf(os.Stderr, "Found unhandled constants in %s", f.Path)
+		var traits map[string][]string
+		if f.Class != nil || f.Trait != nil {
+			traits = usedTraits(sources[f.Path])
 		}
-		if len(f.Functions) > 0 {
-			fmt.Fprintf(os.Stderr, "Found unhandled functions in %s", f.Path)
+
+		for _, c := range f.Constants {
+			if !opts.visible(c.Visibility, c.Docblock) {
+				continue
+			}
+			ns := c.Namespace
+			if !inNamespace(ns, rootNamespace) {
+				fmt.Fprintf(os.Stderr, "Skipping %q which does not belong to namespace %q\n", c.FullName, rootNamespace)
+				continue
+			}
+			members[ns] = append(members[ns], constantItem(c, ns))
+			toc.namespace(ns).UID = ns
+		}
+		for _, fun := range f.Functions {
+			if !opts.visible("", fun.Docblock) {
+				continue
+			}
+			ns := fun.Namespace
+			if !inNamespace(ns, rootNamespace) {
+				fmt.Fprintf(os.Stderr, "Skipping %q which does not belong to namespace %q\n", fun.FullName, rootNamespace)
+				continue
+			}
+			members[ns] = append(members[ns], functionItem(fun, ns))
+			toc.namespace(ns).UID = ns
+			toc.add(ns, &tocItem{
+				Name:   fun.Name + "()",
+				UID:    fun.FullName,
+				Status: fun.Docblock.status(),
+			})
 		}
 
-		if f.Class != nil {
+		if f.Class != nil && opts.visible("", f.Class.Docblock) {
 			classPage := &page{}
 			uid := f.Class.FullName
-			if !strings.HasPrefix(uid, rootNamespace) {
+			ns := namespaceOf(uid)
+			if !inNamespace(ns, rootNamespace) {
 				return nil, nil, fmt.Errorf("found %q which does not belong to namespace %q", uid, rootNamespace)
 			}
-			tocRoot.addItem(&tocItem{
-				Name:   strings.TrimPrefix(uid, rootNamespace),
+			classTOC := &tocItem{
+				Name:   f.Class.Name,
 				UID:    uid,
 				Status: f.Class.Docblock.status(),
-			})
+			}
+			aliasOf := aliasTarget(f.Class)
+			if aliasOf != "" {
+				toc.alias(classTOC)
+			} else {
+				toc.add(ns, classTOC)
+			}
 			classItem := &item{
-				UID:        uid,
-				Name:       f.Class.Name,
-				ID:         f.Class.Name,
-				Summary:    f.Class.Docblock.summary(),
-				Langs:      onlyPHP,
-				Type:       "class",
-				Status:     f.Class.Docblock.status(),
-				Implements: f.Class.Implements,
+				UID:            uid,
+				Name:           f.Class.Name,
+				ID:             f.Class.Name,
+				Summary:        f.Class.Docblock.summary(),
+				Examples:       f.Class.Docblock.examples(),
+				SeeAlso:        seeAlsos(f.Class.Docblock),
+				Langs:          onlyPHP,
+				Type:           "class",
+				Status:         f.Class.Docblock.status(),
+				Implements:     ix.implements(uid),
+				Inheritance:    ix.inheritance(uid),
+				DerivedClasses: ix.derived[uid],
+				UsedTraits:     traits[uid],
+			}
+			values, isEnum := enumValues(f.Class)
+			if isEnum {
+				classItem.Type = "enum"
+				classItem.Values = values
+			}
+			classItem.Service = gapicService(f.Class, ix)
+			// flattened holds the classes whose members are documented as
+			// members of the class, when GAPIC clients are flattened.
+			flattened := map[string]bool{}
+			var bases []string
+			if opts.flattenGapic {
+				bases = flattenedBases(f.Class, ix)
+			}
+			for _, b := range bases {
+				flattened[b] = true
+			}
+			if len(bases) > 0 {
+				classItem.Service = gapicService(ix.classes[bases[0]], ix).scoped(uid)
+			}
+			if aliasOf != "" {
+				classItem.AliasOf = aliasOf
+				classItem.SeeAlso = append(classItem.SeeAlso, seeAlso{LinkType: "CRef", LinkID: aliasOf})
 			}
 			classPage.addItem(classItem)
+			if aliasOf == "" {
+				types[ns] = append(types[ns], summaryRef(classItem, f.Class.Docblock))
+			}
 			if _, ok := pages[uid]; ok {
 				return nil, nil, fmt.Errorf("found duplicate UID: %q", uid)
 			}
 			pages[uid] = classPage
 
 			for _, p := range f.Class.Properties {
+				if !opts.visible(p.Visibility, p.Docblock) {
+					continue
+				}
+				if flattened[p.InheritedFrom] {
+					prop := propertyItem(p)
+					prop.InheritedFrom = p.FullName
+					classItem.Properties = append(classItem.Properties, prop)
+					continue
+				}
 				if p.InheritedFrom != "" {
 					classItem.InheritedMembers = append(classItem.InheritedMembers, p.FullName)
+					classPage.References = append(classPage.References, ix.inheritedRef(p.FullName, p.InheritedFrom, propertyRef(p)))
 					continue
 				}
-				t := ""
-				desc := ""
-				if p.Docblock != nil {
-					// TODO: not first tag
-					if len(p.Docblock.Tags) > 0 {
-						t = p.Docblock.Tags[0].Type
-						if len(p.Docblock.Tags[0].Description) > 0 {
-							fmt.Println("TODO: found a class.tag.description for", p.FullName)
-						}
-					}
-					// TODO p.Default
-					desc = p.Docblock.summary()
+				classItem.Properties = append(classItem.Properties, propertyItem(p))
+			}
+			declaredProps := declaredProperties(opts.visibleProperties(f.Class.Properties))
+			classItem.Properties = append(classItem.Properties, magicProperties(f.Class.Docblock, declaredProps)...)
+			for _, b := range bases {
+				// The magic properties of the bases are inherited too, unless
+				// a nearer class documents a property with the same name.
+				for _, prop := range classItem.Properties {
+					declaredProps[prop.Name] = true
+				}
+				for _, prop := range magicProperties(ix.classes[b].Docblock, declaredProps) {
+					prop.InheritedFrom = b + "::$" + prop.Name
+					classItem.Properties = append(classItem.Properties, prop)
 				}
-				classItem.Properties = append(classItem.Properties, docfxProperty{
-					Name:        p.Name,
-					Type:        t,
-					Description: desc,
-				})
+			}
+			var accessors map[string]bool
+			if ix.isMessage(uid) {
+				classItem.Fields, accessors = messageFields(f.Class)
 			}
 			for _, m := range f.Class.Methods {
+				if !opts.visible(m.Visibility, m.Docblock) || accessors[m.FullName] {
+					continue
+				}
+				if flattened[m.InheritedFrom] {
+					mItem := methodItem(m, uid)
+					mItem.UID = clientScoped(m.FullName, uid)
+					mItem.InheritedFrom = m.FullName
+					classItem.addChild(child(mItem.UID))
+					classPage.addItem(mItem)
+					continue
+				}
 				if m.InheritedFrom != "" {
 					classItem.InheritedMembers = append(classItem.InheritedMembers, m.FullName)
+					classPage.References = append(classPage.References, ix.inheritedRef(m.FullName, m.InheritedFrom, methodRef(m)))
 					continue
 				}
 				mUID := m.FullName
-				mItem := &item{
-					UID:        mUID,
-					Name:       m.Name,
-					ID:         m.Name,
-					Parent:     uid,
-					Summary:    m.Docblock.summary(),
-					Langs:      onlyPHP,
-					Type:       "method",
-					Status:     m.Docblock.status(),
-					Parameters: arguments(m),
-				}
+				mItem := methodItem(m, uid)
 				classItem.addChild(child(mUID))
 				classPage.addItem(mItem)
 			}
+			declaredMeths := declaredMethods(opts.visibleMethods(f.Class.Methods))
+			magicMeths := magicMethods(f.Class.Docblock, uid, declaredMeths)
+			for _, mItem := range magicMeths {
+				classItem.addChild(child(mItem.UID))
+				classPage.addItem(mItem)
+			}
+			for _, b := range bases {
+				// Like magic properties, the magic methods of the bases are
+				// inherited unless a nearer class documents the name.
+				for _, mItem := range magicMeths {
+					declaredMeths[strings.ToLower(mItem.Name)] = true
+				}
+				magicMeths = magicMethods(ix.classes[b].Docblock, b, declaredMeths)
+				for _, mItem := range magicMeths {
+					mItem.InheritedFrom = mItem.UID
+					mItem.UID = clientScoped(mItem.UID, uid)
+					mItem.Parent = uid
+					classItem.addChild(child(mItem.UID))
+					classPage.addItem(mItem)
+				}
+			}
 			for _, c := range f.Class.Constants {
+				if !opts.visible(c.Visibility, c.Docblock) {
+					continue
+				}
+				if flattened[c.InheritedFrom] {
+					cItem := constantItem(c, uid)
+					cItem.UID = clientScoped(c.FullName, uid)
+					cItem.InheritedFrom = c.FullName
+					classItem.addChild(child(cItem.UID))
+					classPage.addItem(cItem)
+					continue
+				}
 				if c.InheritedFrom != "" {
 					classItem.InheritedMembers = append(classItem.InheritedMembers, c.FullName)
+					classPage.References = append(classPage.References, ix.inheritedRef(c.FullName, c.InheritedFrom, constantRef(c)))
 					continue
 				}
-				cUID := c.FullName
-				cItem := &item{
-					UID:     cUID,
-					Name:    c.Name,
-					ID:      c.Name,
-					Parent:  uid,
-					Syntax:  syntax{Content: c.Value},
-					Summary: c.Docblock.summary(),
-					Langs:   onlyPHP,
-					Type:    "constant",
-					Status:  c.Docblock.status(),
+				if isEnum {
+					// Documented by the values of the enum.
+					continue
 				}
+				cUID := c.FullName
+				cItem := constantItem(c, uid)
 				classItem.addChild(child(cUID))
 				classPage.addItem(cItem)
 			}
+			addTraitMembers(classPage, classItem, f.Class.Properties, f.Class.Methods, ix, opts)
 		}
 
-		// TODO: update template to include traits. Leads to broken pages right now.
-		if f.Trait != nil {
+		if f.Trait != nil && opts.visible("", f.Trait.Docblock) {
 			traitPage := &page{}
 			uid := f.Trait.FullName
-			if !strings.HasPrefix(uid, rootNamespace) {
+			ns := namespaceOf(uid)
+			if !inNamespace(ns, rootNamespace) {
 				return nil, nil, fmt.Errorf("found %q which does not belong to namespace %q", uid, rootNamespace)
 			}
-			tocRoot.addItem(&tocItem{
-				Name:   strings.TrimPrefix(uid, rootNamespace),
+			toc.add(ns, &tocItem{
+				Name:   f.Trait.Name,
 				UID:    uid,
 				Status: f.Trait.Docblock.status(),
 			})
 			traitItem := &item{
-				UID:     uid,
-				Name:    f.Trait.Name,
-				ID:      f.Trait.Name,
-				Summary: f.Trait.Docblock.summary(),
-				Langs:   onlyPHP,
-				Type:    "trait",
-				Status:  f.Trait.Docblock.status(),
-				// TODO: f.Trait.Properties,
+				UID:        uid,
+				Name:       f.Trait.Name,
+				ID:         f.Trait.Name,
+				Summary:    f.Trait.Docblock.summary(),
+				Examples:   f.Trait.Docblock.examples(),
+				SeeAlso:    seeAlsos(f.Trait.Docblock),
+				Langs:      onlyPHP,
+				Type:       "trait",
+				Status:     f.Trait.Docblock.status(),
+				UsedTraits: traits[uid],
 			}
 			traitPage.addItem(traitItem)
+			types[ns] = append(types[ns], summaryRef(traitItem, f.Trait.Docblock))
 			if _, ok := pages[uid]; ok {
 				return nil, nil, fmt.Errorf("found duplicate UID: %q", uid)
 			}
 			pages[uid] = traitPage
 
+			for _, p := range f.Trait.Properties {
+				if !opts.visible(p.Visibility, p.Docblock) {
+					continue
+				}
+				if p.InheritedFrom != "" {
+					traitItem.InheritedMembers = append(traitItem.InheritedMembers, p.FullName)
+					traitPage.References = append(traitPage.References, ix.inheritedRef(p.FullName, p.InheritedFrom, propertyRef(p)))
+					continue
+				}
+				traitItem.Properties = append(traitItem.Properties, propertyItem(p))
+			}
+			traitItem.Properties = append(traitItem.Properties, magicProperties(f.Trait.Docblock, declaredProperties(opts.visibleProperties(f.Trait.Properties)))...)
 			for _, m := range f.Trait.Methods {
+				if !opts.visible(m.Visibility, m.Docblock) {
+					continue
+				}
 				if m.InheritedFrom != "" {
 					traitItem.InheritedMembers = append(traitItem.InheritedMembers, m.FullName)
+					traitPage.References = append(traitPage.References, ix.inheritedRef(m.FullName, m.InheritedFrom, methodRef(m)))
 					continue
 				}
 				mUID := m.FullName
-				mItem := &item{
-					UID:        mUID,
-					Name:       m.Name,
-					ID:         m.Name,
-					Parent:     uid,
-					Summary:    m.Docblock.summary(),
-					Langs:      onlyPHP,
-					Type:       "method",
-					Status:     m.Docblock.status(),
-					Parameters: arguments(m),
-				}
+				mItem := methodItem(m, uid)
 				traitItem.addChild(child(mUID))
 				traitPage.addItem(mItem)
 			}
+			for _, mItem := range magicMethods(f.Trait.Docblock, uid, declaredMethods(opts.visibleMethods(f.Trait.Methods))) {
+				traitItem.addChild(child(mItem.UID))
+				traitPage.addItem(mItem)
+			}
+			addTraitMembers(traitPage, traitItem, f.Trait.Properties, f.Trait.Methods, ix, opts)
 		}
 
-		if f.Interface != nil {
+		if f.Interface != nil && opts.visible("", f.Interface.Docblock) {
 			interfacePage := &page{}
 			uid := f.Interface.FullName
-			if !strings.HasPrefix(uid, rootNamespace) {
+			ns := namespaceOf(uid)
+			if !inNamespace(ns, rootNamespace) {
 				return nil, nil, fmt.Errorf("found %q which does not belong to namespace %q", uid, rootNamespace)
 			}
-			tocRoot.addItem(&tocItem{
-				Name:   strings.TrimPrefix(uid, rootNamespace),
+			toc.add(ns, &tocItem{
+				Name:   f.Interface.Name,
 				UID:    uid,
 				Status: f.Interface.Docblock.status(),
 			})
 			interfaceItem := &item{
-				UID:     uid,
-				Name:    f.Interface.Name,
-				ID:      f.Interface.Name,
-				Summary: f.Interface.Docblock.summary(),
-				Langs:   onlyPHP,
-				Type:    "interface",
-				Status:  f.Interface.Docblock.status(),
+				UID:      uid,
+				Name:     f.Interface.Name,
+				ID:       f.Interface.Name,
+				Summary:  f.Interface.Docblock.summary(),
+				Examples: f.Interface.Docblock.examples(),
+				SeeAlso:  seeAlsos(f.Interface.Docblock),
+				Langs:    onlyPHP,
+				Type:     "interface",
+				Status:   f.Interface.Docblock.status(),
+				Extends:  f.Interface.Extends,
 			}
 			interfacePage.addItem(interfaceItem)
+			types[ns] = append(types[ns], summaryRef(interfaceItem, f.Interface.Docblock))
 			if _, ok := pages[uid]; ok {
 				return nil, nil, fmt.Errorf("found duplicate UID: %q", uid)
 			}
 			pages[f.Interface.FullName] = interfacePage
 
 			for _, m := range f.Interface.Methods {
+				if !opts.visible(m.Visibility, m.Docblock) {
+					continue
+				}
 				if m.InheritedFrom != "" {
 					interfaceItem.InheritedMembers = append(interfaceItem.InheritedMembers, m.FullName)
+					interfacePage.References = append(interfacePage.References, ix.inheritedRef(m.FullName, m.InheritedFrom, methodRef(m)))
 					continue
 				}
 				mUID := m.FullName
-				mItem := &item{
-					UID:        mUID,
-					Name:       m.Name,
-					ID:         m.Name,
-					Parent:     uid,
-					Summary:    m.Docblock.summary(),
-					Langs:      onlyPHP,
-					Type:       "method",
-					Status:     m.Docblock.status(),
-					Parameters: arguments(m),
-				}
+				mItem := methodItem(m, uid)
 				interfaceItem.addChild(child(mUID))
 				interfacePage.addItem(mItem)
 			}
+			for _, mItem := range magicMethods(f.Interface.Docblock, uid, declaredMethods(opts.visibleMethods(f.Interface.Methods))) {
+				interfaceItem.addChild(child(mItem.UID))
+				interfacePage.addItem(mItem)
+			}
 			for _, c := range f.Interface.Constants {
+				if !opts.visible(c.Visibility, c.Docblock) {
+					continue
+				}
 				if c.InheritedFrom != "" {
 					interfaceItem.InheritedMembers = append(interfaceItem.InheritedMembers, c.FullName)
+					interfacePage.References = append(interfacePage.References, ix.inheritedRef(c.FullName, c.InheritedFrom, constantRef(c)))
 					continue
 				}
 				cUID := c.FullName
-				cItem := &item{
-					UID:     cUID,
-					Name:    c.Name,
-					ID:      c.Name,
-					Parent:  uid,
-					Syntax:  syntax{Content: c.Value},
-					Summary: c.Docblock.summary(),
-					Langs:   onlyPHP,
-					Type:    "constant",
-					Status:  c.Docblock.status(),
-				}
+				cItem := constantItem(c, uid)
 				interfaceItem.addChild(child(cUID))
 				interfacePage.addItem(cItem)
 			}
 		}
 	}
-	sort.Slice(tocRoot.Items, func(i, j int) bool {
-		return tocRoot.Items[i].UID < tocRoot.Items[j].UID
-	})
-	return pages, tableOfContents{tocRoot}, nil
+
+	// Every namespace with members, and every parent of one, gets an
+	// overview page. Namespaces with the same name as a type (like the
+	// namespace of nested protobuf messages) are represented by the type's
+	// page instead, which lists the types and file-level members of the
+	// namespace.
+	namespaces := map[string]bool{}
+	for ns := range types {
+		for ; ns != rootNamespace; ns = namespaceOf(ns) {
+			namespaces[ns] = true
+		}
+	}
+	for ns := range members {
+		for ; ns != rootNamespace; ns = namespaceOf(ns) {
+			namespaces[ns] = true
+		}
+	}
+	namespaces[rootNamespace] = true
+	for ns := range namespaces {
+		nsTypes := types[ns]
+		sort.Slice(nsTypes, func(i, j int) bool {
+			return nsTypes[i].UID < nsTypes[j].UID
+		})
+		if p, ok := pages[ns]; ok && p.Items[0].Type != "namespace" {
+			for _, m := range members[ns] {
+				p.Items[0].NestedMembers = append(p.Items[0].NestedMembers, m.UID)
+				p.addItem(m)
+			}
+			for _, t := range nsTypes {
+				p.Items[0].NestedTypes = append(p.Items[0].NestedTypes, t.UID)
+				p.References = append(p.References, t)
+			}
+			continue
+		}
+		nsPage, nsItem := namespacePage(pages, ns)
+		for _, m := range members[ns] {
+			nsItem.addChild(child(m.UID))
+			nsPage.addItem(m)
+		}
+		for _, t := range nsTypes {
+			nsItem.addChild(child(t.UID))
+			nsPage.References = append(nsPage.References, t)
+		}
+		toc.namespace(ns).UID = ns
+	}
+
+	for _, p := range pages {
+		p.addReferences(rootNamespace)
+	}
+	return pages, toc.toc(), nil
 }
 
-func arguments(m method) []parameter {
+// inNamespace reports whether ns is rootNamespace or one of its
+// sub-namespaces.
+func inNamespace(ns, rootNamespace string) bool {
+	return ns == rootNamespace || strings.HasPrefix(ns, rootNamespace+"\\")
+}
+
+// namespacePage returns the page for the namespace ns, creating it if
+// needed. Namespace pages hold the file-level members of a namespace.
+func namespacePage(pages map[string]*page, ns string) (*page, *item) {
+	if p, ok := pages[ns]; ok {
+		return p, p.Items[0]
+	}
+	nsItem := &item{
+		UID:   ns,
+		Name:  ns[1:], // Trim leading \.
+		ID:    ns[1:],
+		Langs: onlyPHP,
+		Type:  "namespace",
+	}
+	p := &page{}
+	p.addItem(nsItem)
+	pages[ns] = p
+	return p, nsItem
+}
+
+// summaryRef returns a reference to i with the one line summary of d, for
+// listing i on another page.
+func summaryRef(i *item, d *docblock) *reference {
+	return &reference{
+		UID:      i.UID,
+		Name:     i.Name,
+		FullName: strings.TrimPrefix(i.UID, "\\"),
+		Summary:  d.shortSummary(),
+		Type:     i.Type,
+		Status:   i.Status,
+	}
+}
+
+// methodRef returns a reference to m with its summary and signature.
+func methodRef(m method) *reference {
+	return &reference{
+		UID:      m.FullName,
+		Name:     m.Name,
+		FullName: strings.TrimPrefix(m.FullName, "\\"),
+		Summary:  m.Docblock.shortSummary(),
+		Type:     "method",
+		Status:   m.Docblock.status(),
+		Syntax:   &syntax{Content: methodSignature(m)},
+	}
+}
+
+// propertyRef returns a reference to p with its summary and declaration.
+func propertyRef(p property) *reference {
+	return &reference{
+		UID:      p.FullName,
+		Name:     p.Name,
+		FullName: strings.TrimPrefix(p.FullName, "\\"),
+		Summary:  p.Docblock.shortSummary(),
+		Type:     "property",
+		Status:   p.Docblock.status(),
+		Syntax:   &syntax{Content: propertySignature(p)},
+	}
+}
+
+// constantRef returns a reference to c with its summary and value.
+func constantRef(c constant) *reference {
+	return &reference{
+		UID:      c.FullName,
+		Name:     c.Name,
+		FullName: strings.TrimPrefix(c.FullName, "\\"),
+		Summary:  c.Docblock.shortSummary(),
+		Type:     "constant",
+		Status:   c.Docblock.status(),
+		Syntax:   &syntax{Content: c.Value},
+	}
+}
+
+// deprecatedAlias matches the summary of the classes kept for backwards
+// compatibility when protobuf classes were renamed, like Feature_Type.
+var deprecatedAlias = regexp.MustCompile(`^This class is deprecated\. Use (\S+) instead\.$`)
+
+// aliasTarget returns the UID of the class c is a deprecated alias for, or
+// "" if c is not an alias.
+func aliasTarget(c *class) string {
+	if len(c.Methods) > 0 || len(c.Properties) > 0 || len(c.Constants) > 0 {
+		return ""
+	}
+	m := deprecatedAlias.FindStringSubmatch(c.Docblock.shortSummary())
+	if m == nil {
+		return ""
+	}
+	return "\\" + strings.TrimPrefix(m[1], "\\")
+}
+
+// newReference returns a reference to uid. uid is external if it does not
+// belong to rootNamespace.
+func newReference(uid, rootNamespace string) *reference {
+	name := uid[strings.LastIndex(uid, "\\")+1:]
+	if i := strings.Index(uid, "::"); i >= 0 {
+		name = uid[i+len("::"):]
+	}
+	return &reference{
+		UID:        uid,
+		Name:       name,
+		FullName:   strings.TrimPrefix(uid, "\\"),
+		IsExternal: !strings.HasPrefix(uid, rootNamespace+"\\"),
+	}
+}
+
+// typeUIDs returns the UIDs of the classes and interfaces in the PHPDoc type
+// t. For example, "\Foo\Bar[]|string|null" mentions \Foo\Bar.
+func typeUIDs(t string) []string {
+	var uids []string
+	for _, part := range strings.Split(t, "|") {
+		part = strings.TrimPrefix(part, "?")
+		for strings.HasSuffix(part, "[]") {
+			part = strings.TrimSuffix(part, "[]")
+		}
+		if strings.HasPrefix(part, "\\") {
+			uids = append(uids, part)
+		}
+	}
+	return uids
+}
+
+// namespaceOf returns the namespace of the given fully qualified name.
+func namespaceOf(fullName string) string {
+	return fullName[:strings.LastIndex(fullName, "\\")]
+}
+
+func methodItem(m method, parent string) *item {
+	return &item{
+		UID:      m.FullName,
+		Name:     m.Name,
+		ID:       m.Name,
+		Parent:   parent,
+		Summary:  m.Docblock.summary(),
+		Examples: m.Docblock.examples(),
+		SeeAlso:  seeAlsos(m.Docblock),
+		Langs:    onlyPHP,
+		Type:     "method",
+		Status:   m.Docblock.status(),
+		Syntax: syntax{
+			Content: methodSignature(m),
+			Return:  returns(m.Docblock),
+		},
+		Parameters: arguments(m.Arguments, m.Docblock),
+		Exceptions: exceptions(m.Docblock),
+	}
+}
+
+func functionItem(f fn, parent string) *item {
+	return &item{
+		UID:      f.FullName,
+		Name:     f.Name,
+		ID:       f.Name,
+		Parent:   parent,
+		Summary:  f.Docblock.summary(),
+		Examples: f.Docblock.examples(),
+		SeeAlso:  seeAlsos(f.Docblock),
+		Langs:    onlyPHP,
+		Type:     "function",
+		Status:   f.Docblock.status(),
+		Syntax: syntax{
+			Content: functionSignature(f),
+			Return:  returns(f.Docblock),
+		},
+		Parameters: arguments(f.Arguments, f.Docblock),
+		Exceptions: exceptions(f.Docblock),
+	}
+}
+
+func constantItem(c constant, parent string) *item {
+	i := &item{
+		UID:      c.FullName,
+		Name:     c.Name,
+		ID:       c.Name,
+		Parent:   parent,
+		Syntax:   syntax{Content: c.Value},
+		Summary:  c.Docblock.summary(),
+		Examples: c.Docblock.examples(),
+		SeeAlso:  seeAlsos(c.Docblock),
+		Langs:    onlyPHP,
+		Type:     "constant",
+		Status:   c.Docblock.status(),
+	}
+	if v := c.Docblock.tag("var"); v != nil && v.Type != "" {
+		i.Syntax.Return = &returnValue{Type: v.Type}
+	}
+	return i
+}
+
+// propertyItem documents p, typed by its @var tag.
+func propertyItem(p property) docfxProperty {
+	prop := docfxProperty{
+		Name:         p.Name,
+		Description:  p.Docblock.summary(),
+		DefaultValue: p.defaultValue(),
+		SeeAlso:      seeAlsos(p.Docblock),
+	}
+	if v := p.Docblock.tag("var"); v != nil {
+		prop.Type = v.Type
+		if prop.Description == "" {
+			prop.Description = v.Description
+		}
+	}
+	return prop
+}
+
+// declaredMethods returns the lower case names of methods. PHP method names
+// are case insensitive.
+func declaredMethods(methods []method) map[string]bool {
+	names := map[string]bool{}
+	for _, m := range methods {
+		names[strings.ToLower(m.Name)] = true
+	}
+	return names
+}
+
+// declaredProperties returns the names of props.
+func declaredProperties(props []property) map[string]bool {
+	names := map[string]bool{}
+	for _, p := range props {
+		names[p.Name] = true
+	}
+	return names
+}
+
+// addTraitMembers lists the members i receives from the traits it uses as
+// inherited members, with references on pg. props and methods are the
+// members declared by i, which take precedence over trait members with the
+// same name.
+func addTraitMembers(pg *page, i *item, props []property, methods []method, ix *index, opts options) {
+	declaredProps := declaredProperties(props)
+	declared := declaredMethods(methods)
+	for _, uid := range i.UsedTraits {
+		t, ok := ix.traits[uid]
+		if !ok {
+			continue
+		}
+		for _, p := range t.Properties {
+			if p.InheritedFrom == "" && opts.visible(p.Visibility, p.Docblock) && !declaredProps[p.Name] {
+				i.InheritedMembers = append(i.InheritedMembers, p.FullName)
+				pg.References = append(pg.References, ix.inheritedRef(p.FullName, uid, propertyRef(p)))
+			}
+		}
+		for _, m := range t.Methods {
+			if m.InheritedFrom == "" && opts.visible(m.Visibility, m.Docblock) && !declared[strings.ToLower(m.Name)] {
+				i.InheritedMembers = append(i.InheritedMembers, m.FullName)
+				pg.References = append(pg.References, ix.inheritedRef(m.FullName, uid, methodRef(m)))
+			}
+		}
+	}
+}
+
+// returns returns the @return value documented by d, if any.
+func returns(d *docblock) *returnValue {
+	r := d.tag("return")
+	if r == nil {
+		return nil
+	}
+	return &returnValue{
+		Type:        r.Type,
+		Description: r.Description,
+	}
+}
+
+// exceptions returns the exceptions documented by @throws tags in d.
+func exceptions(d *docblock) []exception {
+	var excs []exception
+	for _, t := range d.tags("throws") {
+		excs = append(excs, exception{
+			Type:        t.Type,
+			Description: t.Description,
+		})
+	}
+	return excs
+}
+
+func arguments(args []argument, d *docblock) []parameter {
 	params := []parameter{}
-	for _, a := range m.Arguments {
+	for _, a := range args {
+		def := a.defaultValue()
+		desc := d.param(a.Name)
+		var props []parameter
+		if shapeDesc, shapeProps, ok := parseArrayShape(desc); ok {
+			desc, props = shapeDesc, shapeProps
+		}
 		params = append(params, parameter{
-			Name:        a.Name,
-			Type:        a.Type,
-			Description: m.Docblock.param(a.Name),
-			// TODO: by reference, default
+			Name:         a.Name,
+			Type:         a.Type,
+			Description:  desc,
+			DefaultValue: def,
+			ByReference:  a.ByReference,
+			Optional:     def != "",
+			Properties:   props,
 		})
 	}
 	return params
 }
 
-// tableOfContents represents a TOC.
-type tableOfContents []*tocItem
+// parseArrayShape parses a description using phpDocumentor's array shape
+// syntax into the description of the array itself and its documented keys.
+// For example:
+//
+//	{
+//	    Optional.
+//
+//	    @type int $pageSize
+//	          The maximum number of resources per page.
+//	}
+//
+// Array shapes can be nested. ok is false if desc is not an array shape.
+func parseArrayShape(desc string) (summary string, props []parameter, ok bool) {
+	desc = strings.TrimSpace(desc)
+	if !strings.HasPrefix(desc, "{") || strings.HasPrefix(desc, "{@") || !strings.HasSuffix(desc, "}") {
+		return "", nil, false
+	}
+	inner := desc[1 : len(desc)-1]
 
-// tocItem is an item in a TOC.
-type tocItem struct {
-	UID    string     `yaml:"uid,omitempty"`
-	Name   string     `yaml:"name,omitempty"`
-	Items  []*tocItem `yaml:"items,omitempty"`
-	Href   string     `yaml:"href,omitempty"`
-	Status string     `yaml:"status,omitempty"`
+	// Split inner on the @type tags that are not nested in braces.
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		switch inner[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				// The leading { was closed before the end of desc.
+				return "", nil, false
+			}
+		case '@':
+			if depth == 0 && strings.HasPrefix(inner[i:], "@type ") {
+				parts = append(parts, inner[start:i])
+				start = i
+			}
+		}
+	}
+	if depth != 0 {
+		return "", nil, false
+	}
+	parts = append(parts, inner[start:])
+
+	summary = collapseSpace(parts[0])
+	for _, part := range parts[1:] {
+		fields := strings.Fields(strings.TrimPrefix(part, "@type "))
+		if len(fields) < 2 {
+			continue
+		}
+		prop := parameter{
+			Type: fields[0],
+			Name: strings.TrimPrefix(fields[1], "$"),
+		}
+		rest := strings.Join(fields[2:], " ")
+		if shapeDesc, shapeProps, ok := parseArrayShape(rest); ok {
+			prop.Description, prop.Properties = shapeDesc, shapeProps
+		} else {
+			prop.Description = rest
+		}
+		props = append(props, prop)
+	}
+	return summary, props, true
 }
 
-func (t *tocItem) addItem(i *tocItem) {
-	t.Items = append(t.Items, i)
+// collapseSpace replaces every run of whitespace in s with a single space.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
 }
 
 // page represents a single DocFX page.
 //
 // There is one page per package.
 type page struct {
-	Items      []*item `yaml:"items"`
-	References []*item `yaml:"references,omitempty"`
+	Items      []*item      `yaml:"items"`
+	References []*reference `yaml:"references,omitempty"`
+}
+
+// reference represents a reference to an item that is not on the page,
+// which DocFX uses to resolve links.
+type reference struct {
+	UID        string `yaml:"uid"`
+	Name       string `yaml:"name,omitempty"`
+	FullName   string `yaml:"fullName,omitempty"`
+	Summary    string `yaml:"summary,omitempty"`
+	Type       string `yaml:"type,omitempty"`
+	Status     string `yaml:"status,omitempty"`
+	IsExternal bool   `yaml:"isExternal,omitempty"`
+	// Parent and Syntax are only set for inherited members, to describe
+	// them on the page of the inheriting type.
+	Parent string  `yaml:"parent,omitempty"`
+	Syntax *syntax `yaml:"syntax,omitempty"`
 }
 
 // child represents an item child.
@@ -300,7 +913,20 @@ type child string
 
 // syntax represents syntax.
 type syntax struct {
-	Content string `yaml:"content,omitempty"`
+	Content string       `yaml:"content,omitempty"`
+	Return  *returnValue `yaml:"return,omitempty"`
+}
+
+// returnValue represents the value returned by a method or function.
+type returnValue struct {
+	Type        string `yaml:"type,omitempty"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// exception represents an exception thrown by a method or function.
+type exception struct {
+	Type        string `yaml:"type,omitempty"`
+	Description string `yaml:"description,omitempty"`
 }
 
 type example struct {
@@ -308,42 +934,152 @@ type example struct {
 	Name    string `yaml:"name,omitempty"`
 }
 
+// seeAlso represents a link to related documentation.
+type seeAlso struct {
+	// LinkType is HRef for URLs and CRef for UIDs.
+	LinkType string `yaml:"linkType"`
+	LinkID   string `yaml:"linkId"`
+	AltText  string `yaml:"altText,omitempty"`
+}
+
 type docfxProperty struct {
-	Type        string `yaml:"type,omitempty"`
-	Name        string `yaml:"name,omitempty"`
-	Description string `yaml:"description,omitempty"`
+	Type         string `yaml:"type,omitempty"`
+	Name         string `yaml:"name,omitempty"`
+	Description  string `yaml:"description,omitempty"`
+	DefaultValue string `yaml:"defaultValue,omitempty"`
+	// Mode is the access allowed to magic properties, like read-only.
+	Mode    string    `yaml:"mode,omitempty"`
+	SeeAlso []seeAlso `yaml:"seealso,omitempty"`
+	// InheritedFrom is the UID of the property in the GAPIC client, or one of
+	// its ancestors, it is inherited from, when GAPIC clients are flattened.
+	InheritedFrom string `yaml:"inheritedFrom,omitempty"`
 }
 
 type parameter struct {
-	Type        string `yaml:"type,omitempty"`
-	Name        string `yaml:"name,omitempty"`
-	Description string `yaml:"description,omitempty"`
+	Type         string      `yaml:"type,omitempty"`
+	Name         string      `yaml:"name,omitempty"`
+	Description  string      `yaml:"description,omitempty"`
+	DefaultValue string      `yaml:"defaultValue,omitempty"`
+	ByReference  bool        `yaml:"byReference,omitempty"`
+	Optional     bool        `yaml:"optional,omitempty"`
+	Properties   []parameter `yaml:"properties,omitempty"`
 }
 
 // item represents a DocFX item.
 type item struct {
-	UID              string          `yaml:"uid"`
-	Name             string          `yaml:"name,omitempty"`
-	ID               string          `yaml:"id,omitempty"`
-	Summary          string          `yaml:"summary,omitempty"`
-	Parent           string          `yaml:"parent,omitempty"`
-	Type             string          `yaml:"type,omitempty"`
-	Langs            []string        `yaml:"langs,omitempty"`
-	Syntax           syntax          `yaml:"syntax,omitempty"`
-	Examples         []example       `yaml:"codeexamples,omitempty"`
-	Children         []child         `yaml:"children,omitempty"`
-	AltLink          string          `yaml:"alt_link,omitempty"`
-	Status           string          `yaml:"status,omitempty"`
-	Implements       []string        `yaml:"implements,omitempty"`
-	InheritedMembers []string        `yaml:"inheritedMembers,omitempty"`
-	Properties       []docfxProperty `yaml:"properties,omitempty"`
-	Parameters       []parameter     `yaml:"parameters,omitempty"`
+	UID              string    `yaml:"uid"`
+	Name             string    `yaml:"name,omitempty"`
+	ID               string    `yaml:"id,omitempty"`
+	Summary          string    `yaml:"summary,omitempty"`
+	Parent           string    `yaml:"parent,omitempty"`
+	Type             string    `yaml:"type,omitempty"`
+	Langs            []string  `yaml:"langs,omitempty"`
+	Syntax           syntax    `yaml:"syntax,omitempty"`
+	Examples         []example `yaml:"codeexamples,omitempty"`
+	Children         []child   `yaml:"children,omitempty"`
+	AltLink          string    `yaml:"alt_link,omitempty"`
+	Status           string    `yaml:"status,omitempty"`
+	AliasOf          string    `yaml:"aliasOf,omitempty"`
+	Implements       []string  `yaml:"implements,omitempty"`
+	Extends          []string  `yaml:"extends,omitempty"`
+	Inheritance      []string  `yaml:"inheritance,omitempty"`
+	DerivedClasses   []string  `yaml:"derivedClasses,omitempty"`
+	UsedTraits       []string  `yaml:"usedTraits,omitempty"`
+	InheritedMembers []string  `yaml:"inheritedMembers,omitempty"`
+	// NestedTypes are the types of the namespace with the same name as the
+	// type, like the nested messages of a protobuf message.
+	NestedTypes []string `yaml:"nestedTypes,omitempty"`
+	// NestedMembers are the file-level constants and functions of the
+	// namespace with the same name as the type.
+	NestedMembers []string `yaml:"nestedMembers,omitempty"`
+	// InheritedFrom is the UID of the member in the GAPIC client, or one of
+	// its ancestors, it is inherited from, when GAPIC clients are flattened.
+	InheritedFrom string          `yaml:"inheritedFrom,omitempty"`
+	Properties    []docfxProperty `yaml:"properties,omitempty"`
+	Parameters    []parameter     `yaml:"parameters,omitempty"`
+	Exceptions    []exception     `yaml:"exceptions,omitempty"`
+	SeeAlso       []seeAlso       `yaml:"seealso,omitempty"`
+	Fields        []field         `yaml:"fields,omitempty"`
+	Values        []enumValue     `yaml:"values,omitempty"`
+	Service       *service        `yaml:"service,omitempty"`
 }
 
 func (p *page) addItem(i *item) {
 	p.Items = append(p.Items, i)
 }
 
+// addReferences adds a reference for every UID mentioned by the items of p
+// that is not already an item or reference of p. The references are sorted
+// by UID.
+func (p *page) addReferences(rootNamespace string) {
+	seen := map[string]bool{}
+	for _, i := range p.Items {
+		seen[i.UID] = true
+	}
+	for _, r := range p.References {
+		seen[r.UID] = true
+	}
+	add := func(uids ...string) {
+		for _, uid := range uids {
+			if !seen[uid] {
+				seen[uid] = true
+				p.References = append(p.References, newReference(uid, rootNamespace))
+			}
+		}
+	}
+	addSeeAlso := func(links []seeAlso) {
+		for _, l := range links {
+			if l.LinkType == "CRef" {
+				add(l.LinkID)
+			}
+		}
+	}
+	for _, i := range p.Items {
+		add(i.Implements...)
+		add(i.Extends...)
+		add(i.Inheritance...)
+		add(i.DerivedClasses...)
+		add(i.UsedTraits...)
+		add(i.InheritedMembers...)
+		if i.InheritedFrom != "" {
+			add(i.InheritedFrom)
+		}
+		addSeeAlso(i.SeeAlso)
+		for _, prop := range i.Properties {
+			add(typeUIDs(prop.Type)...)
+			addSeeAlso(prop.SeeAlso)
+			if prop.InheritedFrom != "" {
+				add(prop.InheritedFrom)
+			}
+		}
+		for _, param := range i.Parameters {
+			add(typeUIDs(param.Type)...)
+		}
+		for _, f := range i.Fields {
+			add(typeUIDs(f.Type)...)
+		}
+		if s := i.Service; s != nil {
+			for _, r := range s.RPCs {
+				if r.Request != "" {
+					add(r.Request)
+				}
+				if r.Response != "" {
+					add(r.Response)
+				}
+			}
+		}
+		if r := i.Syntax.Return; r != nil {
+			add(typeUIDs(r.Type)...)
+		}
+		for _, e := range i.Exceptions {
+			add(typeUIDs(e.Type)...)
+		}
+	}
+	sort.Slice(p.References, func(i, j int) bool {
+		return p.References[i].UID < p.References[j].UID
+	})
+}
+
 func (i *item) addChild(c child) {
 	i.Children = append(i.Children, c)
 }
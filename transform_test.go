@@ -0,0 +1,791 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTransformFunctions(t *testing.T) {
+	p := &project{
+		Files: []file{
+			{
+				Path: "src/functions.php",
+				Functions: []fn{
+					{
+						Namespace: `\Foo\Helpers`,
+						Name:      "slugify",
+						FullName:  `\Foo\Helpers\slugify()`,
+						Docblock: &docblock{
+							Description: "Turns a string into a slug.",
+							Tags: []tag{
+								{Name: "param", Variable: "s", Type: "string", Description: "The input."},
+								{Name: "return", Type: "string", Description: "The slug."},
+								{Name: "deprecated"},
+							},
+						},
+						Arguments: []argument{{Name: "s", Type: "string"}},
+					},
+				},
+			},
+		},
+	}
+	pages, toc, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+
+	nsPage, ok := pages[`\Foo\Helpers`]
+	if !ok {
+		t.Fatalf("no page for namespace %q", `\Foo\Helpers`)
+	}
+	if got, want := len(nsPage.Items), 2; got != want {
+		t.Fatalf("namespace page got %d items, want %d", got, want)
+	}
+	if got, want := nsPage.Items[0].Children, []child{`\Foo\Helpers\slugify()`}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("namespace children got %v, want %v", got, want)
+	}
+	fItem := nsPage.Items[1]
+	if fItem.Type != "function" || fItem.Status != "deprecated" || fItem.Parent != `\Foo\Helpers` {
+		t.Errorf("got function item %+v", fItem)
+	}
+	if len(fItem.Parameters) != 1 || fItem.Parameters[0].Description != "The input." {
+		t.Errorf("got parameters %+v", fItem.Parameters)
+	}
+	if r := fItem.Syntax.Return; r == nil || r.Type != "string" || r.Description != "The slug." {
+		t.Errorf("got return %+v", r)
+	}
+
+	if len(toc[0].Items) != 1 {
+		t.Fatalf("got %d TOC items, want 1", len(toc[0].Items))
+	}
+	nsTOC := toc[0].Items[0]
+	if nsTOC.UID != `\Foo\Helpers` || len(nsTOC.Items) != 1 || nsTOC.Items[0].UID != `\Foo\Helpers\slugify()` {
+		t.Errorf("got TOC %+v", nsTOC)
+	}
+}
+
+func TestTransformFunctionOutsideNamespace(t *testing.T) {
+	p := &project{
+		Files: []file{
+			{
+				Path:      "src/functions.php",
+				Functions: []fn{{Namespace: `\Bar`, Name: "baz", FullName: `\Bar\baz()`}},
+			},
+			{
+				Path:      "src/helpers.php",
+				Constants: []constant{{Namespace: `\`, Name: "FOO_VERSION", FullName: `\FOO_VERSION`, Value: "'1.2.3'"}},
+				Functions: []fn{{Namespace: `\`, Name: "foo", FullName: `\foo()`}},
+			},
+		},
+	}
+	pages, _, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	for uid := range pages {
+		if uid != `\Foo` {
+			t.Errorf("got page %q, want the members outside of the root namespace skipped", uid)
+		}
+	}
+}
+
+func TestTransformConstants(t *testing.T) {
+	p := &project{
+		Files: []file{{
+			Path: "src/constants.php",
+			Constants: []constant{
+				{
+					Namespace: `\Foo`,
+					Name:      "VERSION",
+					FullName:  `\Foo\VERSION`,
+					Value:     "'1.2.3'",
+					Docblock: &docblock{
+						Description: "The library version.",
+						Tags:        []tag{{Name: "var", Type: "string"}},
+					},
+				},
+				{
+					Namespace: `\Foo`,
+					Name:      "OLD_VERSION",
+					FullName:  `\Foo\OLD_VERSION`,
+					Value:     "'1.0.0'",
+					Docblock:  &docblock{Tags: []tag{{Name: "deprecated"}}},
+				},
+			},
+		}},
+	}
+	pages, toc, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+
+	nsPage, ok := pages[`\Foo`]
+	if !ok {
+		t.Fatalf("no page for namespace %q", `\Foo`)
+	}
+	if got, want := len(nsPage.Items), 3; got != want {
+		t.Fatalf("namespace page got %d items, want %d", got, want)
+	}
+	if got := nsPage.Items[0].Children; len(got) != 2 {
+		t.Errorf("namespace got children %v, want 2", got)
+	}
+	c := nsPage.Items[1]
+	if c.Type != "constant" || c.Syntax.Content != "'1.2.3'" || c.Summary != "The library version." {
+		t.Errorf("got constant item %+v", c)
+	}
+	if r := c.Syntax.Return; r == nil || r.Type != "string" {
+		t.Errorf("got constant type %+v, want string", r)
+	}
+	if got := nsPage.Items[2].Status; got != "deprecated" {
+		t.Errorf("got status %q, want deprecated", got)
+	}
+	if toc[0].UID != `\Foo` {
+		t.Errorf("got TOC root UID %q, want %q", toc[0].UID, `\Foo`)
+	}
+}
+
+func TestParseArrayShape(t *testing.T) {
+	desc := `{     Optional.      @type int $pageSize           The maximum number of
+	resources.     @type array $retrySettings { Retry settings.  @type int $retries  Max {@see Foo} retries. }
+	}`
+	summary, props, ok := parseArrayShape(desc)
+	if !ok {
+		t.Fatalf("parseArrayShape(%q) got ok false", desc)
+	}
+	if want := "Optional."; summary != want {
+		t.Errorf("got summary %q, want %q", summary, want)
+	}
+	if len(props) != 2 {
+		t.Fatalf("got %d properties, want 2: %+v", len(props), props)
+	}
+	if got, want := props[0], (parameter{Type: "int", Name: "pageSize", Description: "The maximum number of resources."}); got.Type != want.Type || got.Name != want.Name || got.Description != want.Description {
+		t.Errorf("got property %+v, want %+v", got, want)
+	}
+	nested := props[1]
+	if nested.Name != "retrySettings" || nested.Description != "Retry settings." || len(nested.Properties) != 1 {
+		t.Fatalf("got nested property %+v", nested)
+	}
+	if got, want := nested.Properties[0].Description, "Max {@see Foo} retries."; got != want {
+		t.Errorf("got nested description %q, want %q", got, want)
+	}
+
+	for _, desc := range []string{"", "The name.", "{@see Foo} and {@see Bar}", "{ unbalanced"} {
+		if _, _, ok := parseArrayShape(desc); ok {
+			t.Errorf("parseArrayShape(%q) got ok true, want false", desc)
+		}
+	}
+}
+
+func TestTransformVisibility(t *testing.T) {
+	p := &project{
+		Files: []file{{
+			Path: "src/Client.php",
+			Class: &class{
+				Name:     "Client",
+				FullName: `\Foo\Client`,
+				Methods: []method{
+					{Name: "publicMethod", FullName: `\Foo\Client::publicMethod()`, Visibility: "public"},
+					{Name: "protectedMethod", FullName: `\Foo\Client::protectedMethod()`, Visibility: "protected"},
+					{Name: "privateMethod", FullName: `\Foo\Client::privateMethod()`, Visibility: "private"},
+					{
+						Name:       "accessPrivate",
+						FullName:   `\Foo\Client::accessPrivate()`,
+						Visibility: "public",
+						Docblock:   &docblock{Tags: []tag{{Name: "access", Description: "private"}}},
+					},
+					{
+						Name:       "internal",
+						FullName:   `\Foo\Client::internal()`,
+						Visibility: "public",
+						Docblock:   &docblock{Tags: []tag{{Name: "internal"}}},
+					},
+				},
+				Properties: []property{
+					{Name: "info", FullName: `\Foo\Client::$info`, Visibility: "protected"},
+				},
+			},
+		}},
+	}
+
+	tests := []struct {
+		opts     options
+		children []child
+		props    int
+	}{
+		{
+			opts:     options{},
+			children: []child{`\Foo\Client::publicMethod()`},
+		},
+		{
+			opts:     options{includeProtected: true},
+			children: []child{`\Foo\Client::publicMethod()`, `\Foo\Client::protectedMethod()`},
+			props:    1,
+		},
+	}
+	for _, test := range tests {
+		pages, _, err := transform(p, `\Foo`, test.opts)
+		if err != nil {
+			t.Fatalf("transform: %v", err)
+		}
+		classItem := pages[`\Foo\Client`].Items[0]
+		if len(classItem.Children) != len(test.children) {
+			t.Errorf("transform(%+v) got children %v, want %v", test.opts, classItem.Children, test.children)
+			continue
+		}
+		for i, c := range test.children {
+			if classItem.Children[i] != c {
+				t.Errorf("transform(%+v) got children %v, want %v", test.opts, classItem.Children, test.children)
+				break
+			}
+		}
+		if got := len(classItem.Properties); got != test.props {
+			t.Errorf("transform(%+v) got %d properties, want %d", test.opts, got, test.props)
+		}
+	}
+}
+
+func TestTransformInterfaceExtends(t *testing.T) {
+	p := &project{
+		Files: []file{
+			{
+				Path:      "src/Readable.php",
+				Interface: &iface{Name: "Readable", FullName: `\Foo\Readable`},
+			},
+			{
+				Path:      "src/Closable.php",
+				Interface: &iface{Name: "Closable", FullName: `\Foo\Closable`},
+			},
+			{
+				Path: "src/Stream.php",
+				Interface: &iface{
+					Name:     "Stream",
+					FullName: `\Foo\Stream`,
+					Extends:  []string{`\Foo\Readable`, `\Foo\Closable`},
+				},
+			},
+			{
+				Path: "src/BaseClient.php",
+				Class: &class{
+					Name:       "BaseClient",
+					FullName:   `\Foo\BaseClient`,
+					Implements: []string{`\Foo\Stream`},
+				},
+			},
+			{
+				Path: "src/Client.php",
+				Class: &class{
+					Name:       "Client",
+					FullName:   `\Foo\Client`,
+					Extends:    `\Foo\BaseClient`,
+					Implements: []string{`\Foo\Closable`, `\JsonSerializable`},
+				},
+			},
+		},
+	}
+	pages, _, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+
+	if got := pages[`\Foo\Stream`].Items[0].Extends; len(got) != 2 || got[0] != `\Foo\Readable` || got[1] != `\Foo\Closable` {
+		t.Errorf("got extends %v, want [\\Foo\\Readable \\Foo\\Closable]", got)
+	}
+	want := []string{`\Foo\Closable`, `\JsonSerializable`, `\Foo\Stream`, `\Foo\Readable`}
+	got := pages[`\Foo\Client`].Items[0].Implements
+	if len(got) != len(want) {
+		t.Fatalf("got implements %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got implements %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTransformTraits(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"Face.php":            "<?php\nnamespace Foo;\n\nclass Face\n{\n    use LikelihoodTrait;\n}\n",
+		"LikelihoodTrait.php": "<?php\nnamespace Foo;\n\ntrait LikelihoodTrait\n{\n}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &project{
+		Files: []file{
+			{
+				Path: "LikelihoodTrait.php",
+				Trait: &trait{
+					Name:     "LikelihoodTrait",
+					FullName: `\Foo\LikelihoodTrait`,
+					Properties: []property{
+						{Name: "levels", FullName: `\Foo\LikelihoodTrait::$levels`, Visibility: "public"},
+						{Name: "secret", FullName: `\Foo\LikelihoodTrait::$secret`, Visibility: "private"},
+						{Name: "threshold", FullName: `\Foo\LikelihoodTrait::$threshold`, Visibility: "public"},
+					},
+					Methods: []method{
+						{Name: "likelihood", FullName: `\Foo\LikelihoodTrait::likelihood()`, Visibility: "public"},
+						{Name: "name", FullName: `\Foo\LikelihoodTrait::name()`, Visibility: "public"},
+					},
+				},
+			},
+			{
+				Path: "Face.php",
+				Class: &class{
+					Name:     "Face",
+					FullName: `\Foo\Face`,
+					Properties: []property{
+						{Name: "threshold", FullName: `\Foo\Face::$threshold`, Visibility: "public"},
+					},
+					Methods: []method{{Name: "name", FullName: `\Foo\Face::name()`, Visibility: "public"}},
+				},
+			},
+		},
+	}
+	pages, _, err := transform(p, `\Foo`, options{sourceDir: dir})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+
+	traitItem := pages[`\Foo\LikelihoodTrait`].Items[0]
+	if len(traitItem.Properties) != 2 || traitItem.Properties[0].Name != "levels" {
+		t.Errorf("got trait properties %+v, want levels and threshold", traitItem.Properties)
+	}
+
+	classPage := pages[`\Foo\Face`]
+	classItem := classPage.Items[0]
+	if got := classItem.UsedTraits; len(got) != 1 || got[0] != `\Foo\LikelihoodTrait` {
+		t.Errorf("got used traits %v, want [\\Foo\\LikelihoodTrait]", got)
+	}
+	wantInherited := []string{`\Foo\LikelihoodTrait::$levels`, `\Foo\LikelihoodTrait::likelihood()`}
+	if got := classItem.InheritedMembers; len(got) != 2 || got[0] != wantInherited[0] || got[1] != wantInherited[1] {
+		t.Errorf("got inherited members %v, want %v", got, wantInherited)
+	}
+	if got := classItem.Properties; len(got) != 1 || got[0].Name != "threshold" {
+		t.Errorf("got class properties %+v, want the redeclared threshold", got)
+	}
+	for _, r := range classPage.References {
+		if r.UID == `\Foo\LikelihoodTrait::likelihood()` && r.Parent != `\Foo\LikelihoodTrait` {
+			t.Errorf("got reference parent %q, want %q", r.Parent, `\Foo\LikelihoodTrait`)
+		}
+	}
+
+	if _, _, err := transform(p, `\Foo`, options{sourceDir: filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("transform got no error for missing source")
+	}
+}
+
+func TestPropertyItem(t *testing.T) {
+	p := property{
+		Name:    "name",
+		Default: "NULL",
+		Docblock: &docblock{Tags: []tag{
+			{Name: "deprecated"},
+			{Name: "var", Type: "string|null", Description: "The name."},
+		}},
+	}
+	want := docfxProperty{Type: "string|null", Name: "name", Description: "The name.", DefaultValue: "null"}
+	if got := propertyItem(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("propertyItem got %+v, want %+v", got, want)
+	}
+}
+
+func TestTransformSeeAlso(t *testing.T) {
+	p := &project{
+		Files: []file{{
+			Path: "src/Client.php",
+			Class: &class{
+				Name:     "Client",
+				FullName: `\Foo\Client`,
+				Docblock: &docblock{Tags: []tag{
+					{Name: "see", LinkOrRef: "https://example.com/docs", Description: "The docs"},
+					{Name: "see", LinkOrRef: `\Foo\Image`},
+				}},
+				Methods: []method{{
+					Name:       "close",
+					FullName:   `\Foo\Client::close()`,
+					Visibility: "public",
+					Docblock:   &docblock{Tags: []tag{{Name: "see", LinkOrRef: `Bar\Stream::close()`}}},
+				}},
+			},
+		}},
+	}
+	pages, _, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	classPage := pages[`\Foo\Client`]
+	wantClass := []seeAlso{
+		{LinkType: "HRef", LinkID: "https://example.com/docs", AltText: "The docs"},
+		{LinkType: "CRef", LinkID: `\Foo\Image`},
+	}
+	if got := classPage.Items[0].SeeAlso; !reflect.DeepEqual(got, wantClass) {
+		t.Errorf("got class see also %+v, want %+v", got, wantClass)
+	}
+	wantMethod := []seeAlso{{LinkType: "CRef", LinkID: `\Bar\Stream::close()`}}
+	if got := classPage.Items[1].SeeAlso; !reflect.DeepEqual(got, wantMethod) {
+		t.Errorf("got method see also %+v, want %+v", got, wantMethod)
+	}
+	refs := map[string]*reference{}
+	for _, r := range classPage.References {
+		refs[r.UID] = r
+	}
+	if r, ok := refs[`\Foo\Image`]; !ok || r.IsExternal {
+		t.Errorf("got reference %+v for \\Foo\\Image, want internal reference", r)
+	}
+	if r, ok := refs[`\Bar\Stream::close()`]; !ok || !r.IsExternal {
+		t.Errorf("got reference %+v for \\Bar\\Stream::close(), want external reference", r)
+	}
+}
+
+func TestTransformFlattenGapic(t *testing.T) {
+	gapic := `\Foo\V1\Gapic\FooGapicClient`
+	inherited := func(c *class) *class {
+		c.Methods = append(c.Methods, method{
+			Name:          "getBar",
+			FullName:      gapic + "::getBar()",
+			Visibility:    "public",
+			InheritedFrom: gapic,
+		})
+		c.Constants = append(c.Constants, constant{
+			Name:          "SERVICE_NAME",
+			FullName:      gapic + "::SERVICE_NAME",
+			Value:         "'foo.v1.Foo'",
+			InheritedFrom: gapic,
+		})
+		return c
+	}
+	p := &project{
+		Files: []file{
+			{
+				Path: "src/V1/Gapic/FooGapicClient.php",
+				Class: &class{
+					Name:      "FooGapicClient",
+					FullName:  gapic,
+					Methods:   []method{{Name: "getBar", FullName: gapic + "::getBar()", Visibility: "public"}},
+					Constants: []constant{{Name: "SERVICE_NAME", FullName: gapic + "::SERVICE_NAME", Value: "'foo.v1.Foo'"}},
+				},
+			},
+			{
+				Path:  "src/V1/GetBarRequest.php",
+				Class: &class{Name: "GetBarRequest", FullName: `\Foo\V1\GetBarRequest`, Extends: protobufMessage},
+			},
+			{
+				Path:  "src/V1/FooClient.php",
+				Class: inherited(&class{Name: "FooClient", FullName: `\Foo\V1\FooClient`, Extends: gapic}),
+			},
+		},
+	}
+
+	pages, _, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	client := pages[`\Foo\V1\FooClient`]
+	if got := len(client.Items); got != 1 {
+		t.Errorf("without flattening got %d items, want 1", got)
+	}
+	if got := client.Items[0].InheritedMembers; len(got) != 2 {
+		t.Errorf("without flattening got inherited members %v, want 2", got)
+	}
+
+	pages, _, err = transform(p, `\Foo`, options{flattenGapic: true})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	client = pages[`\Foo\V1\FooClient`]
+	if got := client.Items[0].InheritedMembers; len(got) != 0 {
+		t.Errorf("got inherited members %v, want none", got)
+	}
+	wantChildren := []child{`\Foo\V1\FooClient::getBar()`, `\Foo\V1\FooClient::SERVICE_NAME`}
+	if got := client.Items[0].Children; !reflect.DeepEqual(got, wantChildren) {
+		t.Errorf("got children %v, want %v", got, wantChildren)
+	}
+	for i, want := range []string{gapic + "::getBar()", gapic + "::SERVICE_NAME"} {
+		if got := client.Items[i+1].InheritedFrom; got != want {
+			t.Errorf("%s got inheritedFrom %q, want %q", client.Items[i+1].UID, got, want)
+		}
+	}
+	s := client.Items[0].Service
+	if s == nil || len(s.RPCs) != 1 || s.RPCs[0].UID != `\Foo\V1\FooClient::getBar()` {
+		t.Errorf("got service %+v, want the RPC getBar() of FooClient", s)
+	}
+}
+
+func TestTransformFlattenGapicAncestors(t *testing.T) {
+	base := `\Foo\V1\Gapic\BaseClient`
+	gapic := `\Foo\V1\Gapic\FooGapicClient`
+	closeMethod := method{Name: "close", FullName: base + "::close()", Visibility: "public", InheritedFrom: base}
+	p := &project{
+		Files: []file{
+			{
+				Path: "src/V1/Gapic/BaseClient.php",
+				Class: &class{
+					Name:     "BaseClient",
+					FullName: base,
+					Docblock: &docblock{Tags: []tag{
+						{Name: "property-read", Variable: "$endpoint", Type: "string", Description: "The endpoint."},
+						{Name: "property-read", Variable: "$name", Type: "string", Description: "The base name."},
+						{Name: "method", MethodName: "ping", Description: "Pings the service."},
+						{Name: "method", MethodName: "magicRpc", Description: "The base RPC."},
+						{Name: "method", MethodName: "close", Description: "Shadowed by close()."},
+					}},
+					Methods: []method{{Name: "close", FullName: base + "::close()", Visibility: "public"}},
+				},
+			},
+			{
+				Path: "src/V1/Gapic/FooGapicClient.php",
+				Class: &class{
+					Name:     "FooGapicClient",
+					FullName: gapic,
+					Extends:  base,
+					Docblock: &docblock{Tags: []tag{
+						{Name: "property-read", Variable: "$name", Type: "string", Description: "The name."},
+						{Name: "method", MethodName: "magicRpc", Description: "Calls the RPC."},
+					}},
+					Methods:   []method{closeMethod},
+					Constants: []constant{{Name: "SERVICE_NAME", FullName: gapic + "::SERVICE_NAME", Value: "'foo.v1.Foo'"}},
+				},
+			},
+			{
+				Path: "src/V1/FooClient.php",
+				Class: &class{
+					Name:     "FooClient",
+					FullName: `\Foo\V1\FooClient`,
+					Extends:  gapic,
+					Methods:  []method{closeMethod},
+					Constants: []constant{{
+						Name:          "SERVICE_NAME",
+						FullName:      gapic + "::SERVICE_NAME",
+						Value:         "'foo.v1.Foo'",
+						InheritedFrom: gapic,
+					}},
+				},
+			},
+		},
+	}
+
+	pages, _, err := transform(p, `\Foo`, options{flattenGapic: true})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	client := pages[`\Foo\V1\FooClient`]
+	if got := client.Items[0].InheritedMembers; len(got) != 0 {
+		t.Errorf("got inherited members %v, want none", got)
+	}
+	wantChildren := []child{
+		`\Foo\V1\FooClient::close()`,
+		`\Foo\V1\FooClient::magicRpc()`,
+		`\Foo\V1\FooClient::ping()`,
+		`\Foo\V1\FooClient::SERVICE_NAME`,
+	}
+	if got := client.Items[0].Children; !reflect.DeepEqual(got, wantChildren) {
+		t.Errorf("got children %v, want %v", got, wantChildren)
+	}
+	for i, want := range []struct {
+		inheritedFrom, summary string
+	}{
+		{base + "::close()", ""},
+		{gapic + "::magicRpc()", "Calls the RPC."},
+		{base + "::ping()", "Pings the service."},
+	} {
+		m := client.Items[i+1]
+		if m.InheritedFrom != want.inheritedFrom || m.Summary != want.summary || m.Parent != `\Foo\V1\FooClient` {
+			t.Errorf("%s got inheritedFrom %q, summary %q, and parent %q, want %q, %q, and the client", m.UID, m.InheritedFrom, m.Summary, m.Parent, want.inheritedFrom, want.summary)
+		}
+	}
+	wantProps := []docfxProperty{
+		{Type: "string", Name: "name", Description: "The name.", Mode: "read-only", InheritedFrom: gapic + "::$name"},
+		{Type: "string", Name: "endpoint", Description: "The endpoint.", Mode: "read-only", InheritedFrom: base + "::$endpoint"},
+	}
+	if got := client.Items[0].Properties; !reflect.DeepEqual(got, wantProps) {
+		t.Errorf("got properties %+v, want %+v", got, wantProps)
+	}
+}
+
+func TestTransformNestedTypes(t *testing.T) {
+	p := &project{
+		Files: []file{
+			{
+				Path:  "src/Annotation.php",
+				Class: &class{Name: "Annotation", FullName: `\Foo\Annotation`},
+			},
+			{
+				Path:  "src/Annotation/Web.php",
+				Class: &class{Name: "Web", FullName: `\Foo\Annotation\Web`, Docblock: &docblock{Description: "A web annotation."}},
+			},
+		},
+	}
+	pages, _, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+
+	annotation := pages[`\Foo\Annotation`]
+	if got := annotation.Items[0].Type; got != "class" {
+		t.Fatalf("got type %q for %s, want class", got, `\Foo\Annotation`)
+	}
+	if got, want := annotation.Items[0].NestedTypes, []string{`\Foo\Annotation\Web`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got nested types %v, want %v", got, want)
+	}
+	for _, r := range annotation.References {
+		if r.UID == `\Foo\Annotation\Web` && r.Summary != "A web annotation." {
+			t.Errorf("got reference %+v, want the summary of Web", r)
+		}
+	}
+}
+
+func TestTransformNestedMembers(t *testing.T) {
+	class := file{
+		Path:  "src/Bar.php",
+		Class: &class{Name: "Bar", FullName: `\Foo\Bar`, Methods: []method{{Name: "qux", FullName: `\Foo\Bar::qux()`, Visibility: "public"}}},
+	}
+	functions := file{
+		Path:      "src/Bar/functions.php",
+		Constants: []constant{{Namespace: `\Foo\Bar`, Name: "VERSION", FullName: `\Foo\Bar\VERSION`, Value: "'1.2.3'"}},
+		Functions: []fn{{Namespace: `\Foo\Bar`, Name: "baz", FullName: `\Foo\Bar\baz()`}},
+	}
+	for _, files := range [][]file{{class, functions}, {functions, class}} {
+		pages, _, err := transform(&project{Files: files}, `\Foo`, options{})
+		if err != nil {
+			t.Fatalf("transform with %s first: %v", files[0].Path, err)
+		}
+		bar := pages[`\Foo\Bar`]
+		if got := bar.Items[0].Type; got != "class" {
+			t.Fatalf("with %s first got type %q for %s, want class", files[0].Path, got, `\Foo\Bar`)
+		}
+		if got, want := bar.Items[0].Children, []child{`\Foo\Bar::qux()`}; !reflect.DeepEqual(got, want) {
+			t.Errorf("with %s first got children %v, want %v", files[0].Path, got, want)
+		}
+		want := []string{`\Foo\Bar\VERSION`, `\Foo\Bar\baz()`}
+		if got := bar.Items[0].NestedMembers; !reflect.DeepEqual(got, want) {
+			t.Errorf("with %s first got nested members %v, want %v", files[0].Path, got, want)
+		}
+		var uids []string
+		for _, i := range bar.Items[1:] {
+			uids = append(uids, i.UID)
+		}
+		if want := []string{`\Foo\Bar::qux()`, `\Foo\Bar\VERSION`, `\Foo\Bar\baz()`}; !reflect.DeepEqual(uids, want) {
+			t.Errorf("with %s first got items %v, want %v", files[0].Path, uids, want)
+		}
+	}
+}
+
+func TestTransformMagicMethodsProxied(t *testing.T) {
+	p := &project{
+		Files: []file{{
+			Path: "src/Client.php",
+			Class: &class{
+				Name:     "Client",
+				FullName: `\Foo\Client`,
+				Docblock: &docblock{Tags: []tag{
+					{Name: "method", MethodName: "annotate", Type: "array", Description: "Annotates an image."},
+					{Name: "method", MethodName: "close", Description: "Closes the client."},
+				}},
+				Methods: []method{
+					{Name: "annotate", FullName: `\Foo\Client::annotate()`, Visibility: "private"},
+					{Name: "close", FullName: `\Foo\Client::close()`, Visibility: "public"},
+					{Name: "__call", FullName: `\Foo\Client::__call()`, Visibility: "public"},
+				},
+			},
+		}},
+	}
+	pages, _, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	var got []string
+	for _, i := range pages[`\Foo\Client`].Items[1:] {
+		got = append(got, i.UID+" "+i.Summary)
+	}
+	want := []string{
+		`\Foo\Client::close() `,
+		`\Foo\Client::__call() `,
+		`\Foo\Client::annotate() Annotates an image.`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got methods %q, want %q", got, want)
+	}
+}
+
+func TestTransformMagicPropertiesBacked(t *testing.T) {
+	p := &project{
+		Files: []file{{
+			Path: "src/Client.php",
+			Class: &class{
+				Name:     "Client",
+				FullName: `\Foo\Client`,
+				Docblock: &docblock{Tags: []tag{
+					{Name: "property-read", Variable: "$foo", Type: `\Foo\Foo`, Description: "The foo."},
+					{Name: "property", Variable: "$bar", Type: "string", Description: "The magic bar."},
+				}},
+				Properties: []property{
+					{Name: "foo", FullName: `\Foo\Client::$foo`, Visibility: "private"},
+					{Name: "bar", FullName: `\Foo\Client::$bar`, Visibility: "public"},
+				},
+				Methods: []method{{Name: "__get", FullName: `\Foo\Client::__get()`, Visibility: "public"}},
+			},
+		}},
+	}
+	pages, _, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	want := []docfxProperty{
+		{Name: "bar"},
+		{Type: `\Foo\Foo`, Name: "foo", Description: "The foo.", Mode: "read-only"},
+	}
+	if got := pages[`\Foo\Client`].Items[0].Properties; !reflect.DeepEqual(got, want) {
+		t.Errorf("got properties %+v, want %+v", got, want)
+	}
+}
+
+func TestTransformKeepsProject(t *testing.T) {
+	p := &project{
+		Files: []file{{
+			Path: "src/Client.php",
+			Class: &class{
+				Name:     "Client",
+				FullName: `\Foo\Client`,
+				Docblock: &docblock{Description: "Creates {@see Image}."},
+				Methods: []method{{
+					Name:       "annotate",
+					FullName:   `\Foo\Client::annotate()`,
+					Visibility: "public",
+					Docblock:   &docblock{Tags: []tag{{Name: "return", Description: "The {@see Image}."}}},
+				}},
+			},
+		}},
+	}
+	pages, _, err := transform(p, `\Foo`, options{})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	if got, want := pages[`\Foo\Client`].Items[0].Summary, `Creates <xref uid="\Foo\Image">Image</xref>.`; got != want {
+		t.Errorf("got summary %q, want %q", got, want)
+	}
+	c := p.Files[0].Class
+	if got := c.Docblock.Description; got != "Creates {@see Image}." {
+		t.Errorf("transform changed the class description to %q", got)
+	}
+	if got := c.Methods[0].Docblock.Tags[0].Description; got != "The {@see Image}." {
+		t.Errorf("transform changed the return description to %q", got)
+	}
+}